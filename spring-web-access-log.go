@@ -0,0 +1,349 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 预定义的访问日志格式
+const (
+	CommonLogFormat   = `%h %l %u %t "%r" %>s %b`
+	CombinedLogFormat = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`
+	JSONLogFormat     = "json"
+)
+
+// accessLogEntry 一次请求的访问日志信息
+type accessLogEntry struct {
+	ctx    WebContext
+	start  time.Time
+	cost   time.Duration
+	status int
+	size   int
+}
+
+// accessLogPart 访问日志模板的一部分
+type accessLogPart func(buf *strings.Builder, e *accessLogEntry)
+
+// AccessLogFilter 访问日志过滤器，可以通过 SetLoggerFilter 替换默认的日志过滤器。
+// 模板支持以下 Apache 风格的指令：%h 客户端 IP，%l 固定为 -，%u 认证通过的
+// 用户名，%t 请求时间，%r 请求行，%s 或者 %>s 状态码，%b 响应体字节数 (0 时为 -)，
+// %B 响应体字节数，%D 耗时微秒数，%T 耗时秒数，%m 请求方法，%U 请求路径，%q 查询
+// 字符串，%H 协议版本，%v 请求的 Host，%R 匹配的路由，%{Name}i 请求头，%{Name}o
+// 响应头，%% 百分号。格式为 JSONLogFormat 时输出 JSON 格式的日志。
+type AccessLogFilter struct {
+	parts   []accessLogPart // 为 nil 时输出 JSON 格式
+	writer  io.Writer       // 为 nil 时使用请求的日志接口输出
+	mutex   sync.Mutex
+	rate    float64    // 采样率，大于等于 1 时记录所有请求
+	exclude *RouteTree // 不记录日志的路由
+}
+
+// NewAccessLogFilter AccessLogFilter 的构造函数，模板格式错误时 panic
+func NewAccessLogFilter(format string) *AccessLogFilter {
+	f := &AccessLogFilter{rate: 1}
+	if format != JSONLogFormat {
+		parts, err := parseAccessLogFormat(format)
+		if err != nil {
+			panic(err)
+		}
+		f.parts = parts
+	}
+	return f
+}
+
+// WithWriter 设置日志的输出目标，每条日志占一行
+func (f *AccessLogFilter) WithWriter(w io.Writer) *AccessLogFilter {
+	f.writer = w
+	return f
+}
+
+// Sample 设置采样率，取值范围 (0,1]，状态码大于等于 500 的请求总是被记录
+func (f *AccessLogFilter) Sample(rate float64) *AccessLogFilter {
+	f.rate = rate
+	return f
+}
+
+// Exclude 添加不记录日志的路由，使用和 ConditionalFilter 相同的路径语法
+func (f *AccessLogFilter) Exclude(patterns ...string) *AccessLogFilter {
+	f.exclude = addFilterPatterns(f.exclude, patterns)
+	return f
+}
+
+// Phase 访问日志过滤器在路由匹配之前执行
+func (f *AccessLogFilter) Phase() FilterPhase {
+	return PreRoutingPhase
+}
+
+// Order 访问日志过滤器和默认的日志过滤器的顺序相同
+func (f *AccessLogFilter) Order() int {
+	return LoggerFilterOrder
+}
+
+func (f *AccessLogFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	start := time.Now()
+
+	// 不是 ResponseWriter 时进行包装，以便获取状态码和响应长度，WebContext
+	// 不支持替换 ResponseWriter 时无法获取
+	w := ctx.ResponseWriter()
+	rw, ok := w.(ResponseWriter)
+	if !ok {
+		rw = NewResponseWriter(w)
+		if setter, ok := ctx.(ResponseWriterSetter); ok {
+			setter.SetResponseWriter(rw)
+			defer func() {
+				rw.WriteHeaderNow()
+				setter.SetResponseWriter(w)
+			}()
+		}
+	}
+
+	chain.Next(ctx)
+
+	e := &accessLogEntry{
+		ctx:    ctx,
+		start:  start,
+		cost:   time.Since(start),
+		status: rw.Status(),
+		size:   rw.Size(),
+	}
+	if f.skip(e) {
+		return
+	}
+
+	var line string
+	if f.parts == nil {
+		line = jsonAccessLog(e)
+	} else {
+		var buf strings.Builder
+		for _, part := range f.parts {
+			part(&buf, e)
+		}
+		line = buf.String()
+	}
+
+	if f.writer == nil {
+		ctx.LogInfo(line)
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	_, _ = io.WriteString(f.writer, line+"\n")
+}
+
+// skip 返回是否不记录该请求
+func (f *AccessLogFilter) skip(e *accessLogEntry) bool {
+	if path := e.ctx.Path(); f.exclude != nil && path != "" && f.exclude.Allowed(path) != 0 {
+		return true
+	}
+	return e.status < 500 && f.rate < 1 && rand.Float64() >= f.rate
+}
+
+// parseAccessLogFormat 解析访问日志模板
+func parseAccessLogFormat(format string) ([]accessLogPart, error) {
+
+	var parts []accessLogPart
+	literal := func(s string) {
+		if s != "" {
+			parts = append(parts, func(buf *strings.Builder, _ *accessLogEntry) {
+				buf.WriteString(s)
+			})
+		}
+	}
+
+	for {
+		i := strings.IndexByte(format, '%')
+		if i < 0 {
+			literal(format)
+			return parts, nil
+		}
+		literal(format[:i])
+		format = format[i+1:]
+
+		// %{Name}i 或者 %{Name}o
+		var name string
+		if strings.HasPrefix(format, "{") {
+			j := strings.IndexByte(format, '}')
+			if j < 0 {
+				return nil, fmt.Errorf("access log format: unclosed '{' in %q", format)
+			}
+			name, format = format[1:j], format[j+1:]
+		}
+
+		format = strings.TrimPrefix(format, ">")
+		if format == "" {
+			return nil, fmt.Errorf("access log format: missing directive after '%%'")
+		}
+
+		part, err := accessLogDirective(format[0], name)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		format = format[1:]
+	}
+}
+
+// accessLogDirective 返回模板指令对应的处理函数
+func accessLogDirective(c byte, name string) (accessLogPart, error) {
+
+	if name != "" {
+		switch c {
+		case 'i':
+			return func(buf *strings.Builder, e *accessLogEntry) {
+				writeOrDash(buf, e.ctx.Request().Header.Get(name))
+			}, nil
+		case 'o':
+			return func(buf *strings.Builder, e *accessLogEntry) {
+				writeOrDash(buf, e.ctx.ResponseWriter().Header().Get(name))
+			}, nil
+		}
+		return nil, fmt.Errorf("access log format: unsupported directive %%{%s}%c", name, c)
+	}
+
+	switch c {
+	case '%':
+		return func(buf *strings.Builder, _ *accessLogEntry) {
+			buf.WriteByte('%')
+		}, nil
+	case 'h':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			writeOrDash(buf, e.ctx.ClientIP())
+		}, nil
+	case 'l':
+		return func(buf *strings.Builder, _ *accessLogEntry) {
+			buf.WriteByte('-')
+		}, nil
+	case 'u':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			user, _, _ := e.ctx.Request().BasicAuth()
+			writeOrDash(buf, user)
+		}, nil
+	case 't':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(e.start.Format("[02/Jan/2006:15:04:05 -0700]"))
+		}, nil
+	case 'r':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			r := e.ctx.Request()
+			buf.WriteString(r.Method + " " + r.RequestURI + " " + r.Proto)
+		}, nil
+	case 's':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(strconv.Itoa(e.status))
+		}, nil
+	case 'b':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			if e.size == 0 {
+				buf.WriteByte('-')
+			} else {
+				buf.WriteString(strconv.Itoa(e.size))
+			}
+		}, nil
+	case 'B':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(strconv.Itoa(e.size))
+		}, nil
+	case 'D':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(strconv.FormatInt(int64(e.cost/time.Microsecond), 10))
+		}, nil
+	case 'T':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(strconv.FormatFloat(e.cost.Seconds(), 'f', 3, 64))
+		}, nil
+	case 'm':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(e.ctx.Request().Method)
+		}, nil
+	case 'U':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(e.ctx.Request().URL.Path)
+		}, nil
+	case 'q':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			if q := e.ctx.Request().URL.RawQuery; q != "" {
+				buf.WriteString("?" + q)
+			}
+		}, nil
+	case 'H':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			buf.WriteString(e.ctx.Request().Proto)
+		}, nil
+	case 'v':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			writeOrDash(buf, e.ctx.Request().Host)
+		}, nil
+	case 'R':
+		return func(buf *strings.Builder, e *accessLogEntry) {
+			writeOrDash(buf, e.ctx.Path())
+		}, nil
+	}
+	return nil, fmt.Errorf("access log format: unsupported directive %%%c", c)
+}
+
+// writeOrDash 写入字符串，为空时写入 -
+func writeOrDash(buf *strings.Builder, s string) {
+	if s == "" {
+		s = "-"
+	}
+	buf.WriteString(s)
+}
+
+// jsonAccessLog 返回 JSON 格式的访问日志
+func jsonAccessLog(e *accessLogEntry) string {
+	r := e.ctx.Request()
+	b, _ := json.Marshal(&struct {
+		Time      string  `json:"time"`
+		ClientIP  string  `json:"client_ip"`
+		Method    string  `json:"method"`
+		Host      string  `json:"host"`
+		URI       string  `json:"uri"`
+		Route     string  `json:"route,omitempty"`
+		Proto     string  `json:"proto"`
+		Status    int     `json:"status"`
+		Size      int     `json:"size"`
+		Latency   float64 `json:"latency_ms"`
+		Referer   string  `json:"referer,omitempty"`
+		UserAgent string  `json:"user_agent,omitempty"`
+		RequestID string  `json:"request_id,omitempty"`
+	}{
+		Time:      e.start.Format(time.RFC3339Nano),
+		ClientIP:  e.ctx.ClientIP(),
+		Method:    r.Method,
+		Host:      r.Host,
+		URI:       r.RequestURI,
+		Route:     e.ctx.Path(),
+		Proto:     r.Proto,
+		Status:    e.status,
+		Size:      e.size,
+		Latency:   float64(e.cost) / float64(time.Millisecond),
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		RequestID: RequestID(e.ctx),
+	})
+	return string(b)
+}
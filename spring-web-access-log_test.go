@@ -0,0 +1,133 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+// syncBuffer 并发安全的 bytes.Buffer
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+// Lines 返回并清空已经写入的行
+func (b *syncBuffer) Lines() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	s := strings.TrimSuffix(b.buf.String(), "\n")
+	b.buf.Reset()
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func TestAccessLogFilter(t *testing.T) {
+
+	t.Run("bad format", func(t *testing.T) {
+		for _, format := range []string{"%", "%{Referer", "%x", "%{Name}s"} {
+			func() {
+				defer func() {
+					assert.Equal(t, recover() != nil, true)
+				}()
+				SpringWeb.NewAccessLogFilter(format)
+			}()
+		}
+	})
+
+	var buf syncBuffer
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	f := SpringWeb.NewAccessLogFilter(`%m %U%q %>s %b %{X-Req}i %{X-Resp}o %R %%`).WithWriter(&buf).Exclude("/health")
+	c.SetLoggerFilter(f)
+
+	c.GetMapping("/user/:id", func(ctx SpringWeb.WebContext) {
+		ctx.Header("X-Resp", "r")
+		ctx.String(http.StatusOK, "hello")
+	})
+	c.GetMapping("/health", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	c.GetMapping("/empty", func(ctx SpringWeb.WebContext) {
+		ctx.NoContent(http.StatusNoContent)
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("template", func(t *testing.T) {
+		doRequest(t, http.MethodGet, url+"/user/1?a=b", "", "X-Req", "q")
+		doRequest(t, http.MethodGet, url+"/health", "")
+		doRequest(t, http.MethodGet, url+"/empty", "")
+		doRequest(t, http.MethodGet, url+"/none", "")
+		assert.Equal(t, buf.Lines(), []string{
+			"GET /user/1?a=b 200 5 q r /user/:id %",
+			"GET /empty 204 - - - /empty %",
+			"GET /none 404 9 - - - %",
+		})
+	})
+
+	// 采样率很低时只记录 5xx 的请求
+	t.Run("json and sample", func(t *testing.T) {
+		var jsonBuf syncBuffer
+		c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+		c.SetLoggerFilter(SpringWeb.NewAccessLogFilter(SpringWeb.JSONLogFormat).WithWriter(&jsonBuf).Sample(0.000001))
+		c.GetMapping("/user/:id", func(ctx SpringWeb.WebContext) {
+			ctx.String(http.StatusOK, "hello")
+		})
+		c.GetMapping("/fail/:id", func(ctx SpringWeb.WebContext) {
+			ctx.String(http.StatusServiceUnavailable, "hello")
+		})
+		c.Start()
+		defer c.Stop(context.Background())
+
+		doRequest(t, http.MethodGet, "http://"+c.Address()+"/user/1", "")
+		doRequest(t, http.MethodGet, "http://"+c.Address()+"/fail/1", "", "User-Agent", "test")
+		lines := jsonBuf.Lines()
+		assert.Equal(t, len(lines), 1)
+
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, m["method"], "GET")
+		assert.Equal(t, m["uri"], "/fail/1")
+		assert.Equal(t, m["route"], "/fail/:id")
+		assert.Equal(t, m["status"], float64(503))
+		assert.Equal(t, m["size"], float64(5))
+		assert.Equal(t, m["user_agent"], "test")
+		assert.Equal(t, m["client_ip"], "127.0.0.1")
+	})
+}
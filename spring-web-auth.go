@@ -0,0 +1,147 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// unauthorized 返回 401 响应
+func unauthorized(ctx WebContext) {
+	code := http.StatusUnauthorized
+	ctx.String(code, "%s", http.StatusText(code))
+}
+
+// BasicAuthFilter HTTP Basic 认证过滤器，认证通过的用户保存在 WebContext 中
+type BasicAuthFilter struct {
+	store CredentialStore
+	realm string
+}
+
+// NewBasicAuthFilter BasicAuthFilter 的构造函数
+func NewBasicAuthFilter(store CredentialStore) *BasicAuthFilter {
+	return &BasicAuthFilter{store: store, realm: "Restricted"}
+}
+
+// Realm 设置认证失败时返回的 realm
+func (f *BasicAuthFilter) Realm(realm string) *BasicAuthFilter {
+	f.realm = realm
+	return f
+}
+
+func (f *BasicAuthFilter) Invoke(ctx WebContext, chain FilterChain) {
+	if username, password, ok := ctx.Request().BasicAuth(); ok {
+		if p, ok := f.store.Authenticate(username, password); ok {
+			ctx.Set(PrincipalKey, p)
+			chain.Next(ctx)
+			return
+		}
+	}
+	ctx.Header(HeaderWWWAuthenticate, "Basic realm="+strconv.Quote(f.realm)+`, charset="UTF-8"`)
+	unauthorized(ctx)
+}
+
+// RegisterSwagger 注册 Basic 认证方式
+func (f *BasicAuthFilter) RegisterSwagger(s *Swagger) {
+	s.AddBasicSecurityDefinition()
+}
+
+// apiKeyLookup API Key 的位置
+type apiKeyLookup struct {
+	in   string // header、query 或者 cookie
+	name string
+}
+
+// APIKeyFilter API Key 认证过滤器，按照添加的顺序从请求头、查询参数或者 Cookie
+// 中查找 API Key，没有指定位置时使用 X-API-Key 请求头
+type APIKeyFilter struct {
+	store   CredentialStore
+	lookups []apiKeyLookup
+}
+
+// NewAPIKeyFilter APIKeyFilter 的构造函数
+func NewAPIKeyFilter(store CredentialStore) *APIKeyFilter {
+	return &APIKeyFilter{store: store}
+}
+
+// Header 从请求头中查找 API Key
+func (f *APIKeyFilter) Header(name string) *APIKeyFilter {
+	f.lookups = append(f.lookups, apiKeyLookup{in: "header", name: name})
+	return f
+}
+
+// Query 从查询参数中查找 API Key
+func (f *APIKeyFilter) Query(name string) *APIKeyFilter {
+	f.lookups = append(f.lookups, apiKeyLookup{in: "query", name: name})
+	return f
+}
+
+// Cookie 从 Cookie 中查找 API Key
+func (f *APIKeyFilter) Cookie(name string) *APIKeyFilter {
+	f.lookups = append(f.lookups, apiKeyLookup{in: "cookie", name: name})
+	return f
+}
+
+// getLookups 返回查找 API Key 的位置
+func (f *APIKeyFilter) getLookups() []apiKeyLookup {
+	if len(f.lookups) == 0 {
+		return []apiKeyLookup{{in: "header", name: "X-API-Key"}}
+	}
+	return f.lookups
+}
+
+// key 返回请求中的 API Key
+func (f *APIKeyFilter) key(ctx WebContext) string {
+	for _, l := range f.getLookups() {
+		var key string
+		switch l.in {
+		case "header":
+			key = ctx.GetHeader(l.name)
+		case "query":
+			key = ctx.QueryParam(l.name)
+		case "cookie":
+			if c, err := ctx.Cookie(l.name); err == nil {
+				key = c.Value
+			}
+		}
+		if key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+func (f *APIKeyFilter) Invoke(ctx WebContext, chain FilterChain) {
+	if key := f.key(ctx); key != "" {
+		if p, ok := f.store.AuthenticateKey(key); ok {
+			ctx.Set(PrincipalKey, p)
+			chain.Next(ctx)
+			return
+		}
+	}
+	unauthorized(ctx)
+}
+
+// RegisterSwagger 注册 ApiKey 认证方式，Swagger 2.0 不支持 Cookie 中的 API Key
+func (f *APIKeyFilter) RegisterSwagger(s *Swagger) {
+	for _, l := range f.getLookups() {
+		if l.in != "cookie" {
+			s.AddApiKeySecurityDefinition(l.name, l.in)
+		}
+	}
+}
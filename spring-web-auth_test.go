@@ -0,0 +1,167 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestFileCredentialStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "credential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "credentials")
+	content := "# users\nbasic alice " + SpringWeb.HashPassword("s3cret", 0) + " admin,user\nbasic bob s3cret\napikey k-123 svc reader\n"
+	if err = ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := SpringWeb.NewFileCredentialStore(file)
+	assert.Equal(t, err, nil)
+
+	p, ok := s.Authenticate("alice", "s3cret")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, p.Roles, []string{"admin", "user"})
+	_, ok = s.Authenticate("bob", "s3cret")
+	assert.Equal(t, ok, true)
+	_, ok = s.Authenticate("alice", "wrong")
+	assert.Equal(t, ok, false)
+	_, ok = s.Authenticate("nobody", "s3cret")
+	assert.Equal(t, ok, false)
+	p, ok = s.AuthenticateKey("k-123")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, p.Name, "svc")
+
+	// 加载失败时保留原来的凭证
+	_ = ioutil.WriteFile(file, []byte("token alice s3cret\n"), 0600)
+	assert.Equal(t, s.Reload() != nil, true)
+	_, ok = s.Authenticate("alice", "s3cret")
+	assert.Equal(t, ok, true)
+
+	// 不支持不加盐的 SHA-256 摘要
+	digest := sha256.Sum256([]byte("s3cret"))
+	_ = ioutil.WriteFile(file, []byte("basic bob sha256:"+hex.EncodeToString(digest[:])+"\n"), 0600)
+	assert.Equal(t, s.Reload() != nil, true)
+
+	_ = ioutil.WriteFile(file, []byte("basic carol "+SpringWeb.HashPassword("pass", 0)+"\n"), 0600)
+	assert.Equal(t, s.Reload(), nil)
+	_, ok = s.Authenticate("alice", "s3cret")
+	assert.Equal(t, ok, false)
+	_, ok = s.Authenticate("carol", "pass")
+	assert.Equal(t, ok, true)
+}
+
+func TestMemoryCredentialStore_Cache(t *testing.T) {
+	store := SpringWeb.NewMemoryCredentialStore()
+	_ = store.AddUser("alice", "s3cret", "admin")
+
+	start := time.Now()
+	_, ok := store.Authenticate("alice", "s3cret")
+	assert.Equal(t, ok, true)
+	verify := time.Since(start)
+
+	// 校验成功之后不再重新计算哈希
+	start = time.Now()
+	p, ok := store.Authenticate("alice", "s3cret")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, p.Roles, []string{"admin"})
+	assert.Equal(t, time.Since(start) < verify/10, true)
+
+	// 修改密码之后缓存失效
+	_ = store.AddUser("alice", "changed", "admin")
+	_, ok = store.Authenticate("alice", "s3cret")
+	assert.Equal(t, ok, false)
+	_, ok = store.Authenticate("alice", "changed")
+	assert.Equal(t, ok, true)
+}
+
+func TestAuthFilter(t *testing.T) {
+
+	store := SpringWeb.NewMemoryCredentialStore()
+	_ = store.AddUser("alice", SpringWeb.HashPassword("s3cret", 0), "admin")
+	store.AddKey("k-123", "svc")
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.Swagger().WithTitle("auth")
+
+	whoami := func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, SpringWeb.GetPrincipal(ctx).Name)
+	}
+	c.GetMapping("/basic", whoami, SpringWeb.NewBasicAuthFilter(store).Realm("test"))
+	c.GetMapping("/key", whoami, SpringWeb.NewAPIKeyFilter(store).Header("X-Token").Query("token").Cookie("token"))
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("basic", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodGet, url+"/basic", "")
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderWWWAuthenticate), `Basic realm="test", charset="UTF-8"`)
+
+		req, _ := http.NewRequest(http.MethodGet, url+"/basic", nil)
+		req.SetBasicAuth("alice", "wrong")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Equal(t, err, nil)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+
+		req.SetBasicAuth("alice", "s3cret")
+		resp, err = http.DefaultClient.Do(req)
+		assert.Equal(t, err, nil)
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, string(b), "alice")
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodGet, url+"/key", "", "X-API-Key", "k-123")
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+		_, body := doRequest(t, http.MethodGet, url+"/key", "", "X-Token", "k-123")
+		assert.Equal(t, body, "svc")
+		_, body = doRequest(t, http.MethodGet, url+"/key?token=k-123", "")
+		assert.Equal(t, body, "svc")
+		_, body = doRequest(t, http.MethodGet, url+"/key", "", "Cookie", "token=k-123")
+		assert.Equal(t, body, "svc")
+		resp, _ = doRequest(t, http.MethodGet, url+"/key?token=wrong", "")
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("swagger", func(t *testing.T) {
+		_, doc := doRequest(t, http.MethodGet, url+"/swagger/doc.json", "")
+		assert.Equal(t, strings.Contains(doc, `"BasicAuth"`), true)
+		assert.Equal(t, strings.Contains(doc, `"X-Token"`), true)
+		assert.Equal(t, strings.Contains(doc, `"token"`), true)
+	})
+}
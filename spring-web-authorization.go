@@ -0,0 +1,224 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// AuthorizationFilterOrder 授权过滤器的顺序，在相同阶段的认证过滤器之后执行
+const AuthorizationFilterOrder = 1000
+
+// PolicyEngine 授权策略，判断认证通过的用户是否拥有角色和权限
+type PolicyEngine interface {
+
+	// HasRole 返回用户是否拥有该角色
+	HasRole(p *Principal, role string) bool
+
+	// HasPermission 返回用户是否拥有该权限
+	HasPermission(ctx WebContext, p *Principal, permission string) bool
+}
+
+// AuthorizationFilter 授权过滤器，检查匹配到的路由通过 Mapper.Secured 和
+// Mapper.RequirePermission 声明的角色和权限。没有认证时返回 401，没有权限
+// 时返回 403，路由没有声明角色和权限时不做检查。声明了角色或者权限的路由没有
+// 一定会执行的 AuthorizationFilter (例如只有被 ConditionalFilter 包装的) 时容器
+// 额外使用 engine 为 nil 的默认过滤器。
+type AuthorizationFilter struct {
+	engine PolicyEngine
+}
+
+// defaultAuthorizationFilter 容器为没有 AuthorizationFilter 的路由使用的默认过滤器，
+// 请求已经通过其他 AuthorizationFilter 的检查时不再重复检查
+var defaultAuthorizationFilter = NewAuthorizationFilter(nil)
+
+// authorizedKey WebContext 中记录请求已经通过授权检查的 Key
+const authorizedKey = "@Authorized"
+
+// NewAuthorizationFilter AuthorizationFilter 的构造函数，engine 为 nil 时只使用
+// Principal 自身的角色，并且不授予任何权限
+func NewAuthorizationFilter(engine PolicyEngine) *AuthorizationFilter {
+	if engine == nil {
+		engine = NewRBACPolicy()
+	}
+	return &AuthorizationFilter{engine: engine}
+}
+
+// Phase 授权过滤器在路由匹配之后执行
+func (f *AuthorizationFilter) Phase() FilterPhase {
+	return PostRoutingPhase
+}
+
+// Order 授权过滤器在认证过滤器之后执行
+func (f *AuthorizationFilter) Order() int {
+	return AuthorizationFilterOrder
+}
+
+func (f *AuthorizationFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	m := GetMapper(ctx)
+	if m == nil || !m.isSecured() {
+		chain.Next(ctx)
+		return
+	}
+
+	if f == defaultAuthorizationFilter && ctx.Get(authorizedKey) != nil {
+		chain.Next(ctx)
+		return
+	}
+
+	p := GetPrincipal(ctx)
+	if p == nil {
+		unauthorized(ctx)
+		return
+	}
+
+	if !f.authorize(ctx, p, m) {
+		code := http.StatusForbidden
+		ctx.String(code, "%s", http.StatusText(code))
+		return
+	}
+
+	ctx.Set(authorizedKey, true)
+	chain.Next(ctx)
+}
+
+// authorize 返回用户是否满足路由声明的角色和权限
+func (f *AuthorizationFilter) authorize(ctx WebContext, p *Principal, m *Mapper) bool {
+
+	if roles := m.GetRoles(); len(roles) > 0 {
+		found := false
+		for _, role := range roles {
+			if f.engine.HasRole(p, role) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, permission := range m.GetPermissions() {
+		if !f.engine.HasPermission(ctx, p, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAuthorizationFilter 返回过滤器列表中是否包含一定会执行的 AuthorizationFilter。
+// ConditionalFilter 等包装过滤器可能跳过被包装的过滤器，因此只穿过 WithOrder 的包装。
+func hasAuthorizationFilter(filters []Filter) bool {
+	for _, f := range filters {
+		for {
+			if _, ok := f.(*AuthorizationFilter); ok {
+				return true
+			}
+			o, ok := f.(*orderedFilter)
+			if !ok {
+				break
+			}
+			f = o.Filter
+		}
+	}
+	return false
+}
+
+// RBACPolicy 基于角色的授权策略，角色可以继承其他角色的权限。权限使用 : 分隔
+// 的多段字符串，例如 book:read，授予的权限中 * 匹配任意一段，末尾的 * 匹配
+// 剩余的所有段，例如 book:* 包含 book:read 和 book:comment:delete。
+type RBACPolicy struct {
+	mutex       sync.RWMutex
+	parents     map[string][]string // 角色继承的角色
+	permissions map[string][]string // 角色拥有的权限
+}
+
+// NewRBACPolicy RBACPolicy 的构造函数
+func NewRBACPolicy() *RBACPolicy {
+	return &RBACPolicy{
+		parents:     make(map[string][]string),
+		permissions: make(map[string][]string),
+	}
+}
+
+// Grant 授予角色权限
+func (r *RBACPolicy) Grant(role string, permissions ...string) *RBACPolicy {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.permissions[role] = append(r.permissions[role], permissions...)
+	return r
+}
+
+// Inherit 设置角色继承的角色，拥有该角色的用户同时拥有继承的角色和权限
+func (r *RBACPolicy) Inherit(role string, parents ...string) *RBACPolicy {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.parents[role] = append(r.parents[role], parents...)
+	return r
+}
+
+// roles 返回用户拥有的所有角色，包括继承的角色
+func (r *RBACPolicy) roles(p *Principal) map[string]bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	roles := make(map[string]bool)
+	stack := append([]string(nil), p.Roles...)
+	for len(stack) > 0 {
+		role := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !roles[role] {
+			roles[role] = true
+			stack = append(stack, r.parents[role]...)
+		}
+	}
+	return roles
+}
+
+func (r *RBACPolicy) HasRole(p *Principal, role string) bool {
+	return r.roles(p)[role]
+}
+
+func (r *RBACPolicy) HasPermission(ctx WebContext, p *Principal, permission string) bool {
+	roles := r.roles(p)
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for role := range roles {
+		for _, granted := range r.permissions[role] {
+			if matchPermission(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPermission 返回授予的权限是否包含需要的权限
+func matchPermission(granted string, required string) bool {
+	g, r := strings.Split(granted, ":"), strings.Split(required, ":")
+	for i, s := range g {
+		if s == "*" && i == len(g)-1 {
+			return len(r) >= len(g)
+		}
+		if i >= len(r) || (s != "*" && s != r[i]) {
+			return false
+		}
+	}
+	return len(g) == len(r)
+}
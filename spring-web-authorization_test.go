@@ -0,0 +1,283 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+// oauth2Filter 在 Swagger 文档中同时注册 BasicAuth 和 OAuth2 认证方式
+type oauth2Filter struct {
+	*SpringWeb.BasicAuthFilter
+}
+
+func (f *oauth2Filter) RegisterSwagger(s *SpringWeb.Swagger) {
+	f.BasicAuthFilter.RegisterSwagger(s)
+	s.AddOauth2ApplicationSecurityDefinition("OAuth2", "https://auth.example.com/token", map[string]string{"user": "user"})
+}
+
+func TestAuthorizationFilter(t *testing.T) {
+
+	store := SpringWeb.NewMemoryCredentialStore()
+	_ = store.AddUser("alice", SpringWeb.HashPassword("a", 1000), "admin")
+	_ = store.AddUser("bob", SpringWeb.HashPassword("b", 1000), "user")
+	_ = store.AddUser("carol", SpringWeb.HashPassword("c", 1000))
+
+	policy := SpringWeb.NewRBACPolicy().
+		Grant("user", "book:read").
+		Grant("admin", "book:*").
+		Inherit("admin", "user")
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.Swagger().WithTitle("authorization")
+	c.AddFilter(SpringWeb.NewAuthorizationFilter(policy))
+
+	basic := SpringWeb.NewBasicAuthFilter(store)
+	oauth2 := &oauth2Filter{basic}
+	ok := func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	}
+	c.GetMapping("/public", ok)
+	c.GetMapping("/books", ok, oauth2).Secured("user").RequirePermission("book:read").Swagger("listBooks")
+	c.DeleteMapping("/books", ok, basic).Secured("admin").RequirePermission("book:delete")
+	c.PutMapping("/books", ok, basic).Secured("user").RequirePermission("book:write")
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+	auth := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+
+	_, body := doRequest(t, http.MethodGet, url+"/public", "")
+	assert.Equal(t, body, "ok")
+
+	for _, testCase := range []struct {
+		method string
+		user   string
+		code   int
+	}{
+		{http.MethodGet, "", http.StatusUnauthorized},
+		{http.MethodGet, "bob:b", http.StatusOK},
+		{http.MethodGet, "alice:a", http.StatusOK},
+		{http.MethodGet, "carol:c", http.StatusForbidden},
+		{http.MethodDelete, "bob:b", http.StatusForbidden},
+		{http.MethodDelete, "alice:a", http.StatusOK},
+		{http.MethodPut, "bob:b", http.StatusForbidden},
+		{http.MethodPut, "alice:a", http.StatusOK},
+	} {
+		var header []string
+		if testCase.user != "" {
+			s := strings.SplitN(testCase.user, ":", 2)
+			header = []string{"Authorization", auth(s[0], s[1])}
+		}
+		resp, _ := doRequest(t, testCase.method, url+"/books", "", header...)
+		assert.Equal(t, resp.StatusCode, testCase.code, testCase.method+" "+testCase.user)
+	}
+
+	_, doc := doRequest(t, http.MethodGet, url+"/swagger/doc.json", "")
+	assert.Equal(t, strings.Contains(doc, `"security":[{"BasicAuth":[]},{"OAuth2":["user","book:read"]}]`), true)
+}
+
+func TestCasbinPolicy(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	model := filepath.Join(dir, "model.conf")
+	_ = ioutil.WriteFile(model, []byte(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`), 0600)
+
+	policy := filepath.Join(dir, "policy.csv")
+	_ = ioutil.WriteFile(policy, []byte(`
+p, reader, book/*, read
+p, admin, book/*, *
+g, alice, admin
+g, admin, reader
+`), 0600)
+
+	p, err := SpringWeb.NewCasbinPolicy(model, policy)
+	assert.Equal(t, err, nil)
+
+	alice := &SpringWeb.Principal{Name: "alice"}
+	bob := &SpringWeb.Principal{Name: "bob", Roles: []string{"reader"}}
+	carol := &SpringWeb.Principal{Name: "carol"}
+
+	assert.Equal(t, p.HasRole(alice, "admin"), true)
+	assert.Equal(t, p.HasRole(alice, "reader"), true)
+	assert.Equal(t, p.HasRole(bob, "admin"), false)
+	assert.Equal(t, p.HasPermission(nil, alice, "book/1:delete"), true)
+	assert.Equal(t, p.HasPermission(nil, bob, "book/1:read"), true)
+	assert.Equal(t, p.HasPermission(nil, bob, "book/1:delete"), false)
+	assert.Equal(t, p.HasPermission(nil, carol, "book/1:read"), false)
+
+	// 策略文件错误时保留原来的策略
+	_ = ioutil.WriteFile(policy, []byte("p, reader, book/*\n"), 0600)
+	assert.Equal(t, p.Reload() != nil, true)
+	assert.Equal(t, p.HasPermission(nil, bob, "book/1:read"), true)
+
+	// eft 为 deny 的规则不授予权限，deny 优先时拒绝访问
+	eftModel := `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = %s
+
+[matchers]
+m = r.sub == p.sub && keyMatch(r.obj, p.obj) && r.act == p.act
+`
+	_ = ioutil.WriteFile(model, []byte(fmt.Sprintf(eftModel, "some(where (p.eft == allow))")), 0600)
+	_ = ioutil.WriteFile(policy, []byte("p, alice, /admin, GET, deny\np, bob, /admin*, GET, allow\np, bob, /admin/users, GET, deny\n"), 0600)
+	p, err = SpringWeb.NewCasbinPolicy(model, policy)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p.Enforce(alice, "/admin", "GET"), false)
+	assert.Equal(t, p.Enforce(bob, "/admin/users", "GET"), true)
+
+	_ = ioutil.WriteFile(model, []byte(fmt.Sprintf(eftModel, "some(where (p.eft == allow)) && !some(where (p.eft == deny))")), 0600)
+	p, err = SpringWeb.NewCasbinPolicy(model, policy)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p.Enforce(alice, "/admin", "GET"), false)
+	assert.Equal(t, p.Enforce(bob, "/admin", "GET"), true)
+	assert.Equal(t, p.Enforce(bob, "/admin/users", "GET"), false)
+
+	_ = ioutil.WriteFile(policy, []byte("p, alice, /admin, GET, maybe\n"), 0600)
+	assert.Equal(t, p.Reload() != nil, true)
+
+	// 不支持的模型
+	_ = ioutil.WriteFile(model, []byte("[request_definition]\nr = sub, obj, act\n[policy_definition]\np = sub, obj, act\n[policy_effect]\ne = !some(where (p.eft == deny))\n[matchers]\nm = r.sub == p.sub\n"), 0600)
+	_, err = SpringWeb.NewCasbinPolicy(model, policy)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestAuthorizationFilter_Default(t *testing.T) {
+
+	store := SpringWeb.NewMemoryCredentialStore()
+	_ = store.AddUser("alice", SpringWeb.HashPassword("a", 1000), "admin")
+	_ = store.AddUser("bob", SpringWeb.HashPassword("b", 1000), "user")
+
+	// 没有添加 AuthorizationFilter 时使用默认的过滤器，只根据 Principal 的角色授权
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewBasicAuthFilter(store))
+
+	ok := func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	}
+	c.GetMapping("/admin", ok).Secured("admin")
+	c.GetMapping("/books", ok).RequirePermission("book:read")
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	// 启动之后注册的路由
+	c.GetMapping("/runtime", ok).Secured("admin")
+
+	url := "http://" + c.Address()
+	for _, testCase := range []struct {
+		path string
+		user string
+		code int
+	}{
+		{"/admin", "bob", http.StatusForbidden},
+		{"/admin", "alice", http.StatusOK},
+		{"/books", "alice", http.StatusForbidden},
+		{"/runtime", "bob", http.StatusForbidden},
+		{"/runtime", "alice", http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, url+testCase.path, nil)
+		req.SetBasicAuth(testCase.user, testCase.user[:1])
+		resp, err := http.DefaultClient.Do(req)
+		assert.Equal(t, err, nil)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, testCase.code, testCase.path+" "+testCase.user)
+	}
+}
+
+func TestAuthorizationFilter_Conditional(t *testing.T) {
+
+	store := SpringWeb.NewMemoryCredentialStore()
+	_ = store.AddUser("alice", SpringWeb.HashPassword("a", 1000), "admin")
+
+	// 被 ConditionalFilter 包装的 AuthorizationFilter 不一定执行，容器仍然使用默认的过滤器
+	policy := SpringWeb.NewRBACPolicy().Grant("admin", "book:*")
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewBasicAuthFilter(store))
+	c.AddFilter(SpringWeb.NewConditionalFilter(SpringWeb.NewAuthorizationFilter(policy)).Include("/admin/*"))
+
+	ok := func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "secret")
+	}
+	c.GetMapping("/api/secret", ok).Secured("admin")
+	c.GetMapping("/api/books", ok).RequirePermission("book:read")
+	c.GetMapping("/admin/books", ok).RequirePermission("book:read")
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+	for _, testCase := range []struct {
+		path string
+		user bool
+		code int
+	}{
+		{"/api/secret", false, http.StatusUnauthorized},
+		{"/api/secret", true, http.StatusOK},
+		{"/api/books", true, http.StatusForbidden}, // 默认的过滤器不授予任何权限
+		{"/admin/books", true, http.StatusOK},      // 已经通过授权检查，默认的过滤器不再检查
+	} {
+		req, _ := http.NewRequest(http.MethodGet, url+testCase.path, nil)
+		if testCase.user {
+			req.SetBasicAuth("alice", "a")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.Equal(t, err, nil)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, testCase.code, testCase.path)
+	}
+}
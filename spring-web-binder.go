@@ -0,0 +1,203 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// bindRequest 绑定请求参数，依次绑定路径参数 (param 标签)、查询参数 (query
+// 标签) 和请求体，请求体根据 Content-Type 选择 json、xml 或者 form 的格式。
+func bindRequest(ctx WebContext, i interface{}) error {
+
+	names, values := ctx.PathParamNames(), ctx.PathParamValues()
+	if len(names) > 0 {
+		params := make(map[string][]string)
+		for j, name := range names {
+			params[name] = []string{values[j]}
+		}
+		if err := bindData(i, params, "param"); err != nil {
+			return err
+		}
+	}
+
+	if err := bindData(i, ctx.QueryParams(), "query"); err != nil {
+		return err
+	}
+
+	r := ctx.Request()
+	if r.ContentLength == 0 {
+		return nil
+	}
+
+	ctype := ctx.ContentType()
+	switch {
+	case strings.HasPrefix(ctype, MIMEApplicationJSON):
+		if err := json.NewDecoder(r.Body).Decode(i); err != nil {
+			return err
+		}
+	case strings.HasPrefix(ctype, MIMEApplicationXML), strings.HasPrefix(ctype, MIMETextXML):
+		if err := xml.NewDecoder(r.Body).Decode(i); err != nil {
+			return err
+		}
+	case strings.HasPrefix(ctype, MIMEApplicationForm), strings.HasPrefix(ctype, MIMEMultipartForm):
+		params, err := ctx.FormParams()
+		if err != nil {
+			return err
+		}
+		if err = bindData(i, params, "form"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported media type %s", ctype)
+	}
+	return nil
+}
+
+// bindData 将 data 中的数据按照 tag 标签绑定到结构体指针 ptr 上
+func bindData(ptr interface{}, data map[string][]string, tag string) error {
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("bind target should be a non-nil pointer")
+	}
+
+	// 非结构体的目标 (例如 map) 不支持这种绑定方式
+	if v = v.Elem(); v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return bindStruct(v, data, tag)
+}
+
+func bindStruct(v reflect.Value, data map[string][]string, tag string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if !fv.CanSet() {
+			continue
+		}
+
+		name, ok := f.Tag.Lookup(tag)
+		if !ok {
+			// 没有标签的嵌入结构体需要递归处理
+			if f.Anonymous && fv.Kind() == reflect.Struct {
+				if err := bindStruct(fv, data, tag); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if name = strings.Split(name, ",")[0]; name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		values, ok := data[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for j, s := range values {
+				if err := setValue(slice.Index(j), s); err != nil {
+					return fmt.Errorf("bind %s error: %v", name, err)
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		if err := setValue(fv, values[0]); err != nil {
+			return fmt.Errorf("bind %s error: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue 将字符串 s 转换成 v 的类型并赋值
+func setValue(v reflect.Value, s string) error {
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"io"
+	"net/http"
+)
+
+// MultipartMemoryKey WebContext 中保存解析 multipart 表单时最大内存的 Key
+const MultipartMemoryKey = "@MultipartMemory"
+
+// ErrBodyTooLarge 请求体超过限制时读取请求体返回的错误
+var ErrBodyTooLarge = NewHttpError(http.StatusRequestEntityTooLarge, "request body too large")
+
+// BodyLimitFilter 限制请求体大小的过滤器，可以添加到容器、Router 和 Mapper 上，
+// 越具体的配置优先级越高，例如 Mapper 上的配置可以放宽容器的限制。Content-Length
+// 超过限制时直接返回 413，否则读取请求体超过限制时返回 ErrBodyTooLarge，处理函数
+// 没有写入响应时返回 413，BIND 形式的处理函数绑定请求参数失败时同样返回 413。
+type BodyLimitFilter struct {
+	limit  int64
+	memory int64
+}
+
+// NewBodyLimitFilter BodyLimitFilter 的构造函数，limit 为请求体的最大字节数
+func NewBodyLimitFilter(limit int64) *BodyLimitFilter {
+	memory := int64(defaultMemory)
+	if limit < memory {
+		memory = limit
+	}
+	return &BodyLimitFilter{limit: limit, memory: memory}
+}
+
+// MultipartMemory 设置解析 multipart 表单时使用的最大内存，超过的部分保存在临时
+// 文件中，默认为 32MB 和 limit 之间的较小值
+func (f *BodyLimitFilter) MultipartMemory(n int64) *BodyLimitFilter {
+	f.memory = n
+	return f
+}
+
+func (f *BodyLimitFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	// 后面还有 BodyLimitFilter 时由其检查 Content-Length
+	r := ctx.Request()
+	if r.ContentLength > f.limit && !hasInnerBodyLimit(chain) {
+		tooLarge(ctx)
+		return
+	}
+
+	ctx.Set(MultipartMemoryKey, f.memory)
+
+	// 外层已经限制过时修改限制的大小，否则包装请求体
+	body, ok := r.Body.(*limitedBody)
+	if ok {
+		body.setLimit(f.limit)
+	} else if r.Body != nil && r.Body != http.NoBody {
+		body = &limitedBody{ReadCloser: r.Body, limit: f.limit}
+		r.Body = body
+	}
+
+	chain.Next(ctx)
+
+	if body != nil && body.exceeded {
+		if w, ok := ctx.ResponseWriter().(ResponseWriter); !ok || !w.Written() {
+			tooLarge(ctx)
+		}
+	}
+}
+
+// hasInnerBodyLimit 返回过滤器链条中剩余的过滤器是否包含 BodyLimitFilter
+func hasInnerBodyLimit(chain FilterChain) bool {
+	var c *DefaultFilterChain
+	switch v := chain.(type) {
+	case *DefaultFilterChain:
+		c = v
+	case *tracedFilterChain:
+		c = &v.DefaultFilterChain
+	default:
+		return false
+	}
+	for _, f := range c.filters[c.next:] {
+		for _, v := range unwrapFilter(f) {
+			if _, ok := v.(*BodyLimitFilter); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bodyTooLarge 返回读取请求体时是否超过了 BodyLimitFilter 的限制
+func bodyTooLarge(r *http.Request) bool {
+	body, ok := r.Body.(*limitedBody)
+	return ok && body.exceeded
+}
+
+// tooLarge 返回 413 响应，并且关闭连接以免继续读取剩余的请求体
+func tooLarge(ctx WebContext) {
+	ctx.Header("Connection", "close")
+	ctx.String(ErrBodyTooLarge.Code, "%s", ErrBodyTooLarge.Message)
+}
+
+// limitedBody 限制读取字节数的请求体
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	ceiling  int64 // 解压后的请求体的最大字节数，BodyLimitFilter 不能放宽，0 表示不限制
+	read     int64
+	exceeded bool
+}
+
+// setLimit 修改限制的大小，不能超过 ceiling
+func (b *limitedBody) setLimit(limit int64) {
+	if b.ceiling > 0 && limit > b.ceiling {
+		limit = b.ceiling
+	}
+	b.limit = limit
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+
+	if b.exceeded || b.read > b.limit {
+		b.exceeded = true
+		return 0, ErrBodyTooLarge
+	}
+
+	// 多读取一个字节以便判断是否超过限制
+	if remain := b.limit - b.read + 1; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		b.exceeded = true
+		return n - int(b.read-b.limit), ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+// doChunkedRequest 发送不带 Content-Length 的请求
+func doChunkedRequest(t *testing.T, url string, body string, header ...string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodPost, url, struct{ io.Reader }{strings.NewReader(body)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestBodyLimitFilter(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewBodyLimitFilter(16))
+
+	echo := func(ctx SpringWeb.WebContext) {
+		b, err := ctx.GetRawData()
+		if err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "%d %v", len(b), ctx.Get(SpringWeb.MultipartMemoryKey))
+	}
+	c.PostMapping("/echo", echo)
+	c.PostMapping("/large", echo, SpringWeb.NewBodyLimitFilter(1024).MultipartMemory(100))
+	c.Route("/r", SpringWeb.NewBodyLimitFilter(32)).PostMapping("/echo", echo)
+	c.PostBinding("/bind", func(ctx context.Context, req *struct{ Name string }) string {
+		return req.Name
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+	small, medium, large := strings.Repeat("x", 16), strings.Repeat("x", 32), strings.Repeat("x", 1000)
+
+	t.Run("content length", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodPost, url+"/echo", small)
+		assert.Equal(t, body, "16 16")
+		resp, body := doRequest(t, http.MethodPost, url+"/echo", medium)
+		assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+		assert.Equal(t, body, "request body too large")
+	})
+
+	t.Run("chunked", func(t *testing.T) {
+		_, body := doChunkedRequest(t, url+"/echo", small)
+		assert.Equal(t, body, "16 16")
+		resp, _ := doChunkedRequest(t, url+"/echo", medium)
+		assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodPost, url+"/r/echo", medium)
+		assert.Equal(t, body, "32 32")
+		resp, _ := doChunkedRequest(t, url+"/r/echo", medium+"x")
+		assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+		_, body = doRequest(t, http.MethodPost, url+"/large", large)
+		assert.Equal(t, body, "1000 100")
+		_, body = doChunkedRequest(t, url+"/large", large)
+		assert.Equal(t, body, "1000 100")
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodPost, url+"/bind", `{"Name":"a"}`, "Content-Type", "application/json")
+		assert.Equal(t, strings.Contains(body, `"data":"a"`), true)
+		resp, body := doChunkedRequest(t, url+"/bind", `{"Name":"`+medium+`"}`, "Content-Type", "application/json")
+		assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+		assert.Equal(t, body, "request body too large")
+	})
+}
@@ -0,0 +1,525 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CasbinPolicy 兼容 Casbin 模型文件和策略文件子集的授权策略。
+//
+// 模型文件的 request_definition 必须有三个字段，依次对应用户名、资源和操作；
+// role_definition 只支持 g = _, _；policy_effect 支持 some(where (p.eft == allow))
+// 和 some(where (p.eft == allow)) && !some(where (p.eft == deny))，policy_definition
+// 包含 eft 字段时其值必须为 allow 或者 deny，没有 eft 字段时所有规则都是 allow；
+// matchers 支持 &&、||、!、==、!=、括号、字符串常量以及 g、keyMatch、keyMatch2 和
+// regexMatch 函数。
+//
+// 策略文件每行一条 CSV 格式的规则，例如 p, admin, book, read 和 g, alice, admin。
+//
+// Mapper.RequirePermission 声明的权限按照最后一个 : 分为资源和操作，例如 book:read，
+// 没有 : 时操作为空字符串。Principal 的角色和策略文件中 g 规则的角色同等对待。
+type CasbinPolicy struct {
+	model    *casbinModel
+	file     string
+	mutex    sync.RWMutex
+	policies [][]string
+	groups   map[string][]string
+}
+
+// NewCasbinPolicy CasbinPolicy 的构造函数，立即加载模型文件和策略文件
+func NewCasbinPolicy(modelFile string, policyFile string) (*CasbinPolicy, error) {
+	m, err := loadCasbinModel(modelFile)
+	if err != nil {
+		return nil, err
+	}
+	c := &CasbinPolicy{model: m, file: policyFile}
+	if err = c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload 重新加载策略文件，加载失败时继续使用原来的策略
+func (c *CasbinPolicy) Reload() error {
+
+	lines, err := readConfigLines(c.file)
+	if err != nil {
+		return err
+	}
+
+	var policies [][]string
+	groups := make(map[string][]string)
+	for _, l := range lines {
+		fields := strings.Split(l.text, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		switch fields[0] {
+		case "p":
+			if len(fields)-1 != len(c.model.p) {
+				return fmt.Errorf("%s:%d: policy needs %d fields", c.file, l.n, len(c.model.p))
+			}
+			if i := c.model.eft; i >= 0 && fields[i+1] != "allow" && fields[i+1] != "deny" {
+				return fmt.Errorf("%s:%d: invalid eft %q", c.file, l.n, fields[i+1])
+			}
+			policies = append(policies, fields[1:])
+		case "g":
+			if len(fields) != 3 {
+				return fmt.Errorf("%s:%d: role needs 2 fields", c.file, l.n)
+			}
+			groups[fields[1]] = append(groups[fields[1]], fields[2])
+		default:
+			return fmt.Errorf("%s:%d: unknown policy type %q", c.file, l.n, fields[0])
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.policies = policies
+	c.groups = groups
+	return nil
+}
+
+// Enforce 返回用户是否可以对资源执行该操作。只有 eft 为 allow 的规则授予权限，
+// 模型使用 deny 优先时匹配到 eft 为 deny 的规则则拒绝访问。
+func (c *CasbinPolicy) Enforce(p *Principal, obj string, act string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	allowed := false
+	e := &casbinEnv{policy: c, principal: p, r: []string{p.Name, obj, act}}
+	for _, e.p = range c.policies {
+		if !c.model.matcher(e) {
+			continue
+		}
+		if c.model.eft < 0 || e.p[c.model.eft] == "allow" {
+			if !c.model.denyOverride {
+				return true
+			}
+			allowed = true
+		} else if c.model.denyOverride {
+			return false
+		}
+	}
+	return allowed
+}
+
+func (c *CasbinPolicy) HasRole(p *Principal, role string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	e := &casbinEnv{policy: c, principal: p, r: []string{p.Name}}
+	return e.g(p.Name, role)
+}
+
+func (c *CasbinPolicy) HasPermission(ctx WebContext, p *Principal, permission string) bool {
+	obj, act := permission, ""
+	if i := strings.LastIndexByte(permission, ':'); i >= 0 {
+		obj, act = permission[:i], permission[i+1:]
+	}
+	return c.Enforce(p, obj, act)
+}
+
+// reaches 返回 g 规则中 from 是否直接或者间接拥有角色 to，调用者需要持有读锁
+func (c *CasbinPolicy) reaches(from string, to string, visited map[string]bool) bool {
+	if from == to {
+		return true
+	}
+	if visited[from] {
+		return false
+	}
+	visited[from] = true
+	for _, next := range c.groups[from] {
+		if c.reaches(next, to, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// casbinEnv 执行 matcher 的环境
+type casbinEnv struct {
+	policy    *CasbinPolicy
+	principal *Principal
+	r         []string
+	p         []string
+}
+
+// g 返回 name 是否拥有角色 role，当前用户的名称还会检查 Principal 的角色
+func (e *casbinEnv) g(name string, role string) bool {
+	if e.policy.reaches(name, role, map[string]bool{}) {
+		return true
+	}
+	if e.principal != nil && name == e.r[0] {
+		for _, r := range e.principal.Roles {
+			if e.policy.reaches(r, role, map[string]bool{}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configLine 配置文件中的一行
+type configLine struct {
+	n    int
+	text string
+}
+
+// readConfigLines 返回配置文件中非空、非注释的行
+func readConfigLines(file string) ([]configLine, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []configLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text != "" && !strings.HasPrefix(text, "#") {
+			lines = append(lines, configLine{n: n, text: text})
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// casbinModel 解析后的模型文件
+type casbinModel struct {
+	r            []string // request_definition 的字段
+	p            []string // policy_definition 的字段
+	eft          int      // policy_definition 中 eft 字段的位置，没有时为 -1
+	denyOverride bool     // 是否 deny 优先
+	matcher      func(e *casbinEnv) bool
+}
+
+// loadCasbinModel 加载模型文件
+func loadCasbinModel(file string) (*casbinModel, error) {
+
+	lines, err := readConfigLines(file)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	var section string
+	for _, l := range lines {
+		if strings.HasPrefix(l.text, "[") && strings.HasSuffix(l.text, "]") {
+			section = l.text[1 : len(l.text)-1]
+			continue
+		}
+		i := strings.IndexByte(l.text, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid line", file, l.n)
+		}
+		key := strings.TrimSpace(l.text[:i])
+		values[section+"."+key] = strings.TrimSpace(l.text[i+1:])
+	}
+
+	fieldsOf := func(key string) []string {
+		var fields []string
+		for _, s := range strings.Split(values[key], ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				fields = append(fields, s)
+			}
+		}
+		return fields
+	}
+
+	m := &casbinModel{
+		r: fieldsOf("request_definition.r"),
+		p: fieldsOf("policy_definition.p"),
+	}
+	if len(m.r) != 3 {
+		return nil, fmt.Errorf("%s: request_definition must have 3 fields", file)
+	}
+	if len(m.p) == 0 {
+		return nil, fmt.Errorf("%s: policy_definition is required", file)
+	}
+	if g, ok := values["role_definition.g"]; ok && strings.Replace(g, " ", "", -1) != "_,_" {
+		return nil, fmt.Errorf("%s: only g = _, _ is supported", file)
+	}
+	m.eft = -1
+	for i, f := range m.p {
+		if f == "eft" {
+			m.eft = i
+		}
+	}
+	switch strings.Replace(values["policy_effect.e"], " ", "", -1) {
+	case "some(where(p.eft==allow))":
+	case "some(where(p.eft==allow))&&!some(where(p.eft==deny))":
+		m.denyOverride = true
+	default:
+		return nil, fmt.Errorf("%s: unsupported policy_effect", file)
+	}
+
+	expr, ok := values["matchers.m"]
+	if !ok {
+		return nil, fmt.Errorf("%s: matchers is required", file)
+	}
+	if m.matcher, err = compileCasbinMatcher(expr, m); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return m, nil
+}
+
+// casbinFuncs matcher 中支持的函数
+var casbinFuncs = map[string]func(a, b string) bool{
+	"keyMatch":   casbinKeyMatch,
+	"keyMatch2":  casbinKeyMatch2,
+	"regexMatch": casbinRegexMatch,
+}
+
+// casbinKeyMatch key2 中的 * 匹配任意字符，例如 /book/* 匹配 /book/1
+func casbinKeyMatch(key1 string, key2 string) bool {
+	i := strings.IndexByte(key2, '*')
+	if i < 0 {
+		return key1 == key2
+	}
+	return strings.HasPrefix(key1, key2[:i])
+}
+
+// casbinKeyParam keyMatch2 中的 :name 参数
+var casbinKeyParam = regexp.MustCompile(`:[^/]+`)
+
+// casbinKeyMatch2 key2 中的 :name 匹配一段路径，* 匹配任意字符
+func casbinKeyMatch2(key1 string, key2 string) bool {
+	key2 = strings.Replace(key2, "/*", "/.*", -1)
+	key2 = casbinKeyParam.ReplaceAllString(key2, "[^/]+")
+	return casbinRegexMatch(key1, "^"+key2+"$")
+}
+
+// casbinRegexMatch key1 是否匹配正则表达式 key2
+func casbinRegexMatch(key1 string, key2 string) bool {
+	ok, err := regexp.MatchString(key2, key1)
+	return err == nil && ok
+}
+
+// compileCasbinMatcher 把 matcher 表达式编译成函数
+func compileCasbinMatcher(expr string, m *casbinModel) (func(e *casbinEnv) bool, error) {
+	tokens, err := casbinTokens(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &casbinParser{tokens: tokens, model: m}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("matcher: unexpected %q", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+// casbinTokens 把 matcher 表达式拆分成词法单元
+func casbinTokens(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||") ||
+			strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case c == '(' || c == ')' || c == ',' || c == '!':
+			tokens = append(tokens, expr[i:i+1])
+			i++
+		case c == '"':
+			j := strings.IndexByte(expr[i+1:], '"')
+			if j < 0 {
+				return nil, fmt.Errorf("matcher: unclosed string")
+			}
+			tokens = append(tokens, expr[i:i+j+2])
+			i += j + 2
+		case c == '_' || c == '.' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9'):
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '.' || ('a' <= expr[j] && expr[j] <= 'z') ||
+				('A' <= expr[j] && expr[j] <= 'Z') || ('0' <= expr[j] && expr[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("matcher: unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+// casbinParser matcher 表达式的递归下降解析器
+type casbinParser struct {
+	tokens []string
+	pos    int
+	model  *casbinModel
+}
+
+// peek 返回下一个词法单元
+func (p *casbinParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// expect 读取指定的词法单元
+func (p *casbinParser) expect(token string) error {
+	if p.peek() != token {
+		return fmt.Errorf("matcher: expect %q but got %q", token, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// or 解析 a || b
+func (p *casbinParser) or() (func(e *casbinEnv) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *casbinEnv) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+// and 解析 a && b
+func (p *casbinParser) and() (func(e *casbinEnv) bool, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *casbinEnv) bool { return l(e) && right(e) }
+	}
+	return left, nil
+}
+
+// unary 解析 !a、(a)、函数调用和比较表达式
+func (p *casbinParser) unary() (func(e *casbinEnv) bool, error) {
+	switch tok := p.peek(); {
+	case tok == "!":
+		p.pos++
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *casbinEnv) bool { return !f(e) }, nil
+	case tok == "(":
+		p.pos++
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case p.pos+1 < len(p.tokens) && p.tokens[p.pos+1] == "(":
+		return p.call()
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	if op != "==" && op != "!=" {
+		return nil, fmt.Errorf("matcher: expect comparison but got %q", op)
+	}
+	p.pos++
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if op == "==" {
+		return func(e *casbinEnv) bool { return left(e) == right(e) }, nil
+	}
+	return func(e *casbinEnv) bool { return left(e) != right(e) }, nil
+}
+
+// call 解析函数调用，函数都有两个参数
+func (p *casbinParser) call() (func(e *casbinEnv) bool, error) {
+	name := p.tokens[p.pos]
+	p.pos += 2
+	a, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(","); err != nil {
+		return nil, err
+	}
+	b, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if name == "g" {
+		return func(e *casbinEnv) bool { return e.g(a(e), b(e)) }, nil
+	}
+	fn, ok := casbinFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("matcher: unsupported function %q", name)
+	}
+	return func(e *casbinEnv) bool { return fn(a(e), b(e)) }, nil
+}
+
+// operand 解析字符串常量或者 r.xxx、p.xxx 形式的字段
+func (p *casbinParser) operand() (func(e *casbinEnv) string, error) {
+	tok := p.peek()
+	p.pos++
+	if strings.HasPrefix(tok, `"`) {
+		s := tok[1 : len(tok)-1]
+		return func(e *casbinEnv) string { return s }, nil
+	}
+	index := func(fields []string, name string) int {
+		for i, f := range fields {
+			if f == name {
+				return i
+			}
+		}
+		return -1
+	}
+	if strings.HasPrefix(tok, "r.") {
+		if i := index(p.model.r, tok[2:]); i >= 0 {
+			return func(e *casbinEnv) string { return e.r[i] }, nil
+		}
+	}
+	if strings.HasPrefix(tok, "p.") {
+		if i := index(p.model.p, tok[2:]); i >= 0 {
+			return func(e *casbinEnv) string { return e.p[i] }, nil
+		}
+	}
+	return nil, fmt.Errorf("matcher: unknown operand %q", tok)
+}
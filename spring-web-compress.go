@@ -0,0 +1,458 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder 压缩响应体的编码器，gzip.Writer 和 zlib.Writer 都满足该接口
+type Encoder interface {
+	io.WriteCloser
+
+	// Flush 把已经压缩的数据写出，用于流式响应
+	Flush() error
+}
+
+// EncoderFactory 创建编码器的函数，level 为压缩级别，含义由具体的编码决定
+type EncoderFactory func(w io.Writer, level int) (Encoder, error)
+
+// DecoderFactory 创建请求体解码器的函数
+type DecoderFactory func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	encodingMutex sync.RWMutex
+	encoders      = map[string]EncoderFactory{}
+	decoders      = map[string]DecoderFactory{}
+)
+
+func init() {
+	RegisterEncoding("gzip",
+		func(w io.Writer, level int) (Encoder, error) {
+			return gzip.NewWriterLevel(w, level)
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		})
+	// HTTP 的 deflate 编码是 zlib 格式 (RFC 1950)，而不是原始的 deflate 数据
+	RegisterEncoding("deflate",
+		func(w io.Writer, level int) (Encoder, error) {
+			return zlib.NewWriterLevel(w, level)
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return zlib.NewReader(r)
+		})
+}
+
+// RegisterEncoding 注册压缩编码，decoder 为 nil 时不支持解压该编码的请求体。默认只
+// 注册了 gzip 和 deflate，标准库不支持 brotli，需要时使用第三方库注册 br 编码，并且
+// 通过 CompressFilter.Encodings 把 br 加入编码的偏好顺序，例如：
+//
+//	RegisterEncoding("br", func(w io.Writer, level int) (Encoder, error) {
+//		return brotli.NewWriterLevel(w, level), nil
+//	}, func(r io.Reader) (io.ReadCloser, error) {
+//		return ioutil.NopCloser(brotli.NewReader(r)), nil
+//	})
+//	NewCompressFilter().Encodings("br", "gzip", "deflate")
+func RegisterEncoding(name string, encoder EncoderFactory, decoder DecoderFactory) {
+	encodingMutex.Lock()
+	defer encodingMutex.Unlock()
+	encoders[name] = encoder
+	if decoder != nil {
+		decoders[name] = decoder
+	}
+}
+
+// getEncoder 返回压缩编码的编码器
+func getEncoder(name string) EncoderFactory {
+	encodingMutex.RLock()
+	defer encodingMutex.RUnlock()
+	return encoders[name]
+}
+
+// getDecoder 返回压缩编码的解码器
+func getDecoder(name string) DecoderFactory {
+	encodingMutex.RLock()
+	defer encodingMutex.RUnlock()
+	return decoders[name]
+}
+
+// defaultMaxDecompressedSize 解压后的请求体默认的最大字节数
+const defaultMaxDecompressedSize = 32 << 20
+
+// CompressFilter 响应压缩过滤器，根据 Accept-Encoding 选择编码，响应体超过阈值
+// 并且不是图片等已经压缩过的类型时才进行压缩。流式响应 (Stream、SSEvent) 在第一次
+// Flush 时决定是否压缩，之后每次 Flush 都会写出已经压缩的数据。解压后的请求体
+// 超过限制时返回 413，无论 BodyLimitFilter 在它之前还是之后执行。
+type CompressFilter struct {
+	level      int
+	minLength  int
+	encodings  []string        // 服务端的编码偏好顺序
+	skipTypes  map[string]bool // 不压缩的 MIME 类型
+	skipPrefix []string        // 不压缩的 MIME 类型前缀，例如 video/
+	decompress bool
+	maxSize    int64 // 解压后的请求体的最大字节数
+}
+
+// NewCompressFilter CompressFilter 的构造函数
+func NewCompressFilter() *CompressFilter {
+	f := &CompressFilter{
+		level:     gzip.DefaultCompression,
+		minLength: 1024,
+		encodings: []string{"gzip", "deflate"},
+		skipTypes: map[string]bool{},
+		maxSize:   defaultMaxDecompressedSize,
+	}
+	f.SkipTypes(MIMEImagePng, MIMEImageJpeg, MIMEImageGif, "image/webp",
+		"application/zip", "application/gzip", "application/x-gzip",
+		"application/x-7z-compressed", "application/x-rar-compressed",
+		"video/*", "audio/*", "font/woff", "font/woff2")
+	return f
+}
+
+// MaxDecompressedSize 设置解压后的请求体的最大字节数，默认为 32MB，用于防止
+// 很小的压缩数据解压成巨大的请求体
+func (f *CompressFilter) MaxDecompressedSize(n int64) *CompressFilter {
+	f.maxSize = n
+	return f
+}
+
+// Level 设置压缩级别，默认为 gzip.DefaultCompression
+func (f *CompressFilter) Level(level int) *CompressFilter {
+	f.level = level
+	return f
+}
+
+// MinLength 设置压缩的最小响应长度，默认为 1024 字节
+func (f *CompressFilter) MinLength(n int) *CompressFilter {
+	f.minLength = n
+	return f
+}
+
+// Encodings 设置编码的偏好顺序，客户端的权重相同时使用靠前的编码，默认为 gzip、
+// deflate。只能使用通过 RegisterEncoding 注册的编码。
+func (f *CompressFilter) Encodings(names ...string) *CompressFilter {
+	f.encodings = names
+	return f
+}
+
+// SkipTypes 添加不压缩的 MIME 类型，支持 video/* 形式的前缀
+func (f *CompressFilter) SkipTypes(types ...string) *CompressFilter {
+	for _, t := range types {
+		if strings.HasSuffix(t, "/*") {
+			f.skipPrefix = append(f.skipPrefix, strings.TrimSuffix(t, "*"))
+		} else {
+			f.skipTypes[t] = true
+		}
+	}
+	return f
+}
+
+// Decompress 设置是否解压 Content-Encoding 为已注册编码的请求体
+func (f *CompressFilter) Decompress(enable bool) *CompressFilter {
+	f.decompress = enable
+	return f
+}
+
+func (f *CompressFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	if f.decompress {
+		body, err := f.decompressRequest(r)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, "%s", err.Error())
+			return
+		}
+		if body != nil {
+			defer func() {
+				if body.exceeded {
+					if w, ok := ctx.ResponseWriter().(ResponseWriter); !ok || !w.Written() {
+						tooLarge(ctx)
+					}
+				}
+			}()
+		}
+	}
+
+	ctx.ResponseWriter().Header().Add(HeaderVary, HeaderAcceptEncoding)
+
+	// WebContext 不支持替换 ResponseWriter 时不压缩响应
+	setter, ok := ctx.(ResponseWriterSetter)
+	encoding := f.negotiate(r.Header.Get(HeaderAcceptEncoding))
+	if !ok || encoding == "" || r.Method == http.MethodHead {
+		chain.Next(ctx)
+		return
+	}
+
+	w := ctx.ResponseWriter()
+	cw := &compressWriter{ResponseWriter: w, filter: f, encoding: encoding, status: http.StatusOK}
+	setter.SetResponseWriter(cw)
+	defer func() {
+		setter.SetResponseWriter(w)
+		if err := cw.Close(); err != nil {
+			ctx.LogError("compress response error: ", err)
+		}
+	}()
+
+	chain.Next(ctx)
+}
+
+// decompressRequest 解压请求体，返回限制了大小的解压后的请求体，请求体没有压缩时
+// 返回 nil。读取解压后的请求体超过限制时返回 ErrBodyTooLarge。
+func (f *CompressFilter) decompressRequest(r *http.Request) (*limitedBody, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get(HeaderContentEncoding)))
+	if encoding == "" || encoding == "identity" {
+		return nil, nil
+	}
+	decoder := getDecoder(encoding)
+	if decoder == nil {
+		return nil, errors.New("unsupported content encoding " + encoding)
+	}
+	decoded, err := decoder(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	body := &limitedBody{ReadCloser: decoded, limit: f.maxSize, ceiling: f.maxSize}
+	r.Body = body
+	r.ContentLength = -1
+	r.Header.Del(HeaderContentEncoding)
+	r.Header.Del(HeaderContentLength)
+	return body, nil
+}
+
+// negotiate 根据 Accept-Encoding 选择权重最高的已注册编码，没有可用的编码时返回空字符串
+func (f *CompressFilter) negotiate(accept string) string {
+
+	if accept == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		name, q := parseQuality(part)
+		if name == "*" {
+			wildcard = q
+		} else if name != "" {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range f.encodings {
+		q, ok := weights[name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ && getEncoder(name) != nil {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// parseQuality 解析 gzip;q=0.8 形式的编码及其权重，没有权重时为 1
+func parseQuality(s string) (string, float64) {
+	name, q := s, 1.0
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		name = s[:i]
+		param := strings.TrimSpace(s[i+1:])
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(name)), q
+}
+
+// skip 返回是否不压缩该类型的响应
+func (f *CompressFilter) skip(contentType string) bool {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if f.skipTypes[contentType] {
+		return true
+	}
+	for _, prefix := range f.skipPrefix {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter 压缩响应体的 ResponseWriter，在写出响应头之前缓存响应体，
+// 以便根据响应长度和类型决定是否压缩
+type compressWriter struct {
+	http.ResponseWriter
+
+	filter   *CompressFilter
+	encoding string
+	encoder  Encoder
+
+	status  int
+	size    int
+	buf     []byte
+	decided bool // 是否已经决定了是否压缩，决定之后写出响应头
+}
+
+// Status 返回响应的状态码
+func (w *compressWriter) Status() int {
+	return w.status
+}
+
+// Size 返回已经写入的未压缩的响应体的字节数
+func (w *compressWriter) Size() int {
+	return w.size
+}
+
+// Written 返回是否已经写入了响应头或者响应体
+func (w *compressWriter) Written() bool {
+	return w.decided || w.size > 0
+}
+
+// WriteHeader 记录状态码，决定是否压缩之后的调用将被忽略
+func (w *compressWriter) WriteHeader(code int) {
+	if code > 0 && !w.decided {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 立即写出响应头，没有响应体的情况下不进行压缩
+func (w *compressWriter) WriteHeaderNow() {
+	w.decide(false)
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.size += len(data)
+
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.filter.minLength {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush 实现 http.Flusher 接口，流式响应在第一次 Flush 时决定是否压缩
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
+}
+
+// Close 写出缓存的响应体并结束压缩，没有写入任何内容时只传递状态码，以便后续
+// 的错误处理函数仍然可以写入响应
+func (w *compressWriter) Close() error {
+	if !w.decided && len(w.buf) == 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+		return nil
+	}
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= w.filter.minLength); err != nil {
+			return err
+		}
+	}
+	if w.encoder != nil {
+		return w.encoder.Close()
+	}
+	return nil
+}
+
+// decide 决定是否压缩，然后写出响应头和缓存的响应体
+func (w *compressWriter) decide(compress bool) error {
+
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+
+	h := w.ResponseWriter.Header()
+	if len(w.buf) > 0 && h.Get(HeaderContentType) == "" {
+		h.Set(HeaderContentType, http.DetectContentType(w.buf))
+	}
+
+	// 没有响应体的状态码和已经编码的响应不再压缩
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		compress = false
+	}
+	if h.Get(HeaderContentEncoding) != "" || w.filter.skip(h.Get(HeaderContentType)) {
+		compress = false
+	}
+
+	if compress {
+		encoder, err := getEncoder(w.encoding)(w.ResponseWriter, w.filter.level)
+		if err != nil {
+			return err
+		}
+		w.encoder = encoder
+		h.Set(HeaderContentEncoding, w.encoding)
+		h.Del(HeaderContentLength)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if rw, ok := w.ResponseWriter.(ResponseWriter); ok {
+		rw.WriteHeaderNow()
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
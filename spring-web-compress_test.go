@@ -0,0 +1,194 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func gunzip(t *testing.T, s string) string {
+	r, err := gzip.NewReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCompressFilter(t *testing.T) {
+
+	large := strings.Repeat("hello world ", 200)
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewCompressFilter().Decompress(true).MaxDecompressedSize(1024))
+
+	c.GetMapping("/large", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "%s", large)
+	})
+	c.GetMapping("/small", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "small")
+	})
+	c.GetMapping("/image", func(ctx SpringWeb.WebContext) {
+		ctx.Blob(http.StatusOK, SpringWeb.MIMEImagePng, []byte(large))
+	})
+	c.GetMapping("/sse", func(ctx SpringWeb.WebContext) {
+		ctx.SSEvent("msg", "one")
+		ctx.SSEvent("msg", "two")
+	})
+	c.HandleGet("/error", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		return SpringWeb.NewHttpError(http.StatusConflict, "conflict")
+	}))
+	c.PostMapping("/echo", func(ctx SpringWeb.WebContext) {
+		b, _ := ioutil.ReadAll(ctx.Request().Body)
+		ctx.String(http.StatusOK, "%s", b)
+	})
+
+	read := func(ctx SpringWeb.WebContext) {
+		b, err := ctx.GetRawData()
+		if err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "%d", len(b))
+	}
+	// 通过 RegisterEncoding 注册的编码
+	SpringWeb.RegisterEncoding("x-flate",
+		func(w io.Writer, level int) (SpringWeb.Encoder, error) {
+			return flate.NewWriter(w, level)
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		})
+	c.GetMapping("/custom", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "%s", large)
+	}, SpringWeb.NewCompressFilter().Encodings("x-flate", "gzip"))
+	c.PostMapping("/read", read)
+	c.PostMapping("/limit", read, SpringWeb.NewBodyLimitFilter(1<<20))
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("gzip", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/large", "", "Accept-Encoding", "deflate;q=0.5, gzip")
+		assert.Equal(t, resp.Header.Get("Content-Encoding"), "gzip")
+		assert.Equal(t, resp.Header.Get("Vary"), "Accept-Encoding")
+		assert.Equal(t, len(body) < len(large), true)
+		assert.Equal(t, gunzip(t, body), large)
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/large", "", "Accept-Encoding", "gzip;q=0, *")
+		assert.Equal(t, resp.Header.Get("Content-Encoding"), "deflate")
+		r, err := zlib.NewReader(strings.NewReader(body))
+		assert.Equal(t, err, nil)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, string(b), large)
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		for _, accept := range []string{"", "br", "gzip;q=0"} {
+			resp, body := doRequest(t, http.MethodGet, url+"/large", "", "Accept-Encoding", accept)
+			assert.Equal(t, resp.Header.Get("Content-Encoding"), "")
+			assert.Equal(t, body, large)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/small", "", "Accept-Encoding", "gzip")
+		assert.Equal(t, resp.Header.Get("Content-Encoding"), "")
+		assert.Equal(t, body, "small")
+		resp, body = doRequest(t, http.MethodGet, url+"/image", "", "Accept-Encoding", "gzip")
+		assert.Equal(t, resp.Header.Get("Content-Encoding"), "")
+		assert.Equal(t, body, large)
+	})
+
+	t.Run("sse", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/sse", "", "Accept-Encoding", "gzip")
+		assert.Equal(t, resp.Header.Get("Content-Encoding"), "gzip")
+		assert.Equal(t, gunzip(t, body), "event: msg\ndata: one\n\nevent: msg\ndata: two\n\n")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/error", "", "Accept-Encoding", "gzip")
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, body, "conflict")
+	})
+
+	t.Run("decompress", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write([]byte(`{"name":"gzip"}`))
+		_ = w.Close()
+		_, body := doRequest(t, http.MethodPost, url+"/echo", buf.String(), "Content-Encoding", "gzip")
+		assert.Equal(t, body, `{"name":"gzip"}`)
+		buf.Reset()
+		z := zlib.NewWriter(&buf)
+		_, _ = z.Write([]byte(`{"name":"deflate"}`))
+		_ = z.Close()
+		_, body = doRequest(t, http.MethodPost, url+"/echo", buf.String(), "Content-Encoding", "deflate")
+		assert.Equal(t, body, `{"name":"deflate"}`)
+		resp, _ := doRequest(t, http.MethodPost, url+"/echo", "x", "Content-Encoding", "zstd")
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("decompress limit", func(t *testing.T) {
+		gz := func(n int) string {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			_, _ = w.Write(make([]byte, n))
+			_ = w.Close()
+			return buf.String()
+		}
+		_, body := doRequest(t, http.MethodPost, url+"/read", gz(1024), "Content-Encoding", "gzip")
+		assert.Equal(t, body, "1024")
+		for _, path := range []string{"/read", "/limit"} {
+			resp, body := doRequest(t, http.MethodPost, url+path, gz(1<<20), "Content-Encoding", "gzip")
+			assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge, path)
+			assert.Equal(t, body, "request body too large")
+		}
+	})
+
+	t.Run("register", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/custom", "", "Accept-Encoding", "x-flate")
+		assert.Equal(t, resp.Header.Get("Content-Encoding"), "x-flate")
+		b, _ := ioutil.ReadAll(flate.NewReader(strings.NewReader(body)))
+		assert.Equal(t, string(b), large)
+
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		_, _ = w.Write([]byte("x-flate"))
+		_ = w.Close()
+		_, body = doRequest(t, http.MethodPost, url+"/echo", buf.String(), "Content-Encoding", "x-flate")
+		assert.Equal(t, body, "x-flate")
+	})
+}
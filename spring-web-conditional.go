@@ -0,0 +1,118 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"strings"
+)
+
+// ConditionalFilter 只对满足条件的路由生效的过滤器，条件包括路径和 HTTP 方法。
+// 路径条件使用和路由相同的语法，匹配的是路由注册的路径 (WebContext.Path) 而不是
+// 请求的原始路径，例如 /user/{id} 匹配所有注册为 /user/:xxx 形式的路由，/swagger/*
+// 匹配 /swagger 下的所有路由。路径的约束条件被忽略。没有匹配到路由的请求只受排除
+// 条件的影响，PreRoutingPhase 阶段的过滤器执行时还没有匹配路由，同样如此。
+type ConditionalFilter struct {
+	filter  Filter
+	include *RouteTree // 为 nil 时包含所有路由
+	exclude *RouteTree // 为 nil 时不排除任何路由
+	method  uint32     // 为 0 时不限制 HTTP 方法
+}
+
+// NewConditionalFilter ConditionalFilter 的构造函数
+func NewConditionalFilter(filter Filter) *ConditionalFilter {
+	return &ConditionalFilter{filter: filter}
+}
+
+// Unwrap 返回被包装的过滤器
+func (f *ConditionalFilter) Unwrap() Filter {
+	return f.filter
+}
+
+// Include 添加需要包含的路径，设置之后只有匹配的路由才执行过滤器
+func (f *ConditionalFilter) Include(patterns ...string) *ConditionalFilter {
+	f.include = addFilterPatterns(f.include, patterns)
+	return f
+}
+
+// Exclude 添加需要排除的路径，排除条件优先于包含条件
+func (f *ConditionalFilter) Exclude(patterns ...string) *ConditionalFilter {
+	f.exclude = addFilterPatterns(f.exclude, patterns)
+	return f
+}
+
+// Method 设置需要执行过滤器的 HTTP 方法
+func (f *ConditionalFilter) Method(method uint32) *ConditionalFilter {
+	f.method = method
+	return f
+}
+
+// addFilterPatterns 把路径条件添加到路由树中，忽略路径的约束条件
+func addFilterPatterns(tree *RouteTree, patterns []string) *RouteTree {
+	if tree == nil {
+		tree = NewRouteTree()
+	}
+	for _, pattern := range patterns {
+		var sb strings.Builder
+		for _, seg := range parsePath(CleanPath(pattern)) {
+			switch seg.kind {
+			case namedSegment:
+				sb.WriteString("/{" + seg.name + "}")
+			case wildCardSegment:
+				sb.WriteString("/*")
+			default:
+				sb.WriteString("/" + seg.name)
+			}
+		}
+		tree.Add(NewMapper(MethodAny, sb.String(), nil, nil))
+	}
+	return tree
+}
+
+// Matches 返回过滤器是否对当前请求生效
+func (f *ConditionalFilter) Matches(ctx WebContext) bool {
+
+	if f.method != 0 && f.method&methodBit(ctx.Request().Method) == 0 {
+		return false
+	}
+
+	path := ctx.Path()
+	if f.exclude != nil && path != "" && f.exclude.Allowed(path) != 0 {
+		return false
+	}
+	if f.include != nil && (path == "" || f.include.Allowed(path) == 0) {
+		return false
+	}
+	return true
+}
+
+func (f *ConditionalFilter) Invoke(ctx WebContext, chain FilterChain) {
+	if f.Matches(ctx) {
+		f.filter.Invoke(ctx, chain)
+	} else {
+		chain.Next(ctx)
+	}
+}
+
+// Phase 使用被包装的过滤器的执行阶段
+func (f *ConditionalFilter) Phase() FilterPhase {
+	return filterPhase(f.filter)
+}
+
+// Order 使用被包装的过滤器的顺序值
+func (f *ConditionalFilter) Order() int {
+	return filterOrder(f.filter)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestConditionalFilter(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.SetEnableSwagger(false)
+
+	c.AddFilter(
+		SpringWeb.NewConditionalFilter(&stringFilter{"a"}).Include("/user/*"),
+		SpringWeb.NewConditionalFilter(&stringFilter{"b"}).Include("/user/*").Exclude("/user/admin"),
+		SpringWeb.NewConditionalFilter(&stringFilter{"m"}).Method(SpringWeb.MethodPost|SpringWeb.MethodPut),
+		SpringWeb.NewConditionalFilter(&stringFilter{"e"}).Exclude("/user/admin"),
+		SpringWeb.NewConditionalFilter(&stringFilter{"k"}).Include("/book/{id:int}"),
+		// 路由匹配之前还不知道注册的路径，包含条件永远不满足
+		SpringWeb.NewConditionalFilter(SpringWeb.WithOrder(&stringFilter{"p"}, SpringWeb.PreRoutingPhase, 0)).Include("/*"),
+	)
+
+	ok := func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	}
+	c.GetMapping("/user/{id}", ok)
+	c.PostMapping("/user/{id}", ok)
+	c.GetMapping("/user/admin", ok)
+	c.GetMapping("/book/:bid", ok)
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		status int
+		filter string
+	}{
+		{http.MethodGet, "/user/1", http.StatusOK, "abe"},   // 通配符包含
+		{http.MethodGet, "/user/admin", http.StatusOK, "a"}, // 排除优先于包含
+		{http.MethodPost, "/user/1", http.StatusOK, "abme"}, // 方法条件
+		{http.MethodGet, "/book/1", http.StatusOK, "ek"},    // 参数名和约束条件被忽略
+		{http.MethodGet, "/none", http.StatusNotFound, "e"}, // 没有匹配到路由时只有排除条件生效
+		{http.MethodDelete, "/user/1", http.StatusMethodNotAllowed, "e"},
+	} {
+		resp, _ := doRequest(t, tc.method, url+tc.path, "")
+		assert.Equal(t, resp.StatusCode, tc.status, tc.method+" "+tc.path)
+		assert.Equal(t, resp.Header.Get("X-Filter"), tc.filter, tc.method+" "+tc.path)
+	}
+}
@@ -17,12 +17,31 @@
 package SpringWeb
 
 const (
-	HeaderContentDisposition = "Content-Disposition"
-	HeaderContentType        = "Content-Type"
-	HeaderXForwardedProto    = "X-Forwarded-Proto"
-	HeaderXForwardedProtocol = "X-Forwarded-Protocol"
-	HeaderXForwardedSsl      = "X-Forwarded-Ssl"
-	HeaderXUrlScheme         = "X-Url-Scheme"
+	HeaderAcceptEncoding                = "Accept-Encoding"
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
+	HeaderAllow                         = "Allow"
+	HeaderAuthorization                 = "Authorization"
+	HeaderContentDisposition            = "Content-Disposition"
+	HeaderContentEncoding               = "Content-Encoding"
+	HeaderContentLength                 = "Content-Length"
+	HeaderContentType                   = "Content-Type"
+	HeaderOrigin                        = "Origin"
+	HeaderVary                          = "Vary"
+	HeaderWWWAuthenticate               = "WWW-Authenticate"
+	HeaderXForwardedFor                 = "X-Forwarded-For"
+	HeaderXForwardedProto               = "X-Forwarded-Proto"
+	HeaderXForwardedProtocol            = "X-Forwarded-Protocol"
+	HeaderXForwardedSsl                 = "X-Forwarded-Ssl"
+	HeaderXRealIP                       = "X-Real-Ip"
+	HeaderXRequestID                    = "X-Request-ID"
+	HeaderXUrlScheme                    = "X-Url-Scheme"
 
 	CharsetUTF8 = "charset=UTF-8"
 
@@ -20,8 +20,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/go-openapi/spec"
 	"github.com/go-spring/go-spring-logger"
 	"github.com/go-spring/go-spring-utils"
 	"github.com/swaggo/http-swagger"
@@ -49,8 +52,29 @@ type ContainerConfig struct {
 
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+
+	// ImplicitHead 没有注册 HEAD 方法时使用 GET 方法的处理函数响应 HEAD 请求，
+	// 响应体被丢弃，但是保留响应头并计算 Content-Length。
+	ImplicitHead bool
+
+	// SlashPolicy 请求路径和路由末尾的 / 不一致时的处理策略
+	SlashPolicy SlashPolicyEnum
+
+	// TrustedProxies 可信的反向代理的 IP 或者 CIDR，只有请求来自可信的代理时
+	// ClientIP 才使用 X-Forwarded-For 和 X-Real-Ip 请求头，默认不信任任何代理。
+	TrustedProxies []string
 }
 
+// SlashPolicyEnum 末尾 / 的处理策略。无论哪种策略，请求路径都会先经过
+// CleanPath 规范化再进行路由匹配。
+type SlashPolicyEnum int
+
+const (
+	StrictSlash    = SlashPolicyEnum(0) // 末尾的 / 必须严格一致
+	RedirectSlash  = SlashPolicyEnum(1) // 重定向到规范的路由地址，GET、HEAD 使用 301，其他方法使用 308
+	MatchBothSlash = SlashPolicyEnum(2) // 末尾有没有 / 都能匹配到路由
+)
+
 // WebContainer Web 容器
 type WebContainer interface {
 	// WebMapping 路由表
@@ -80,6 +104,12 @@ type WebContainer interface {
 	// SetRecoveryFilter 设置 Recovery Filter
 	SetRecoveryFilter(filter Filter)
 
+	// GetErrorHandler 获取错误处理函数
+	GetErrorHandler() ErrorHandler
+
+	// SetErrorHandler 设置错误处理函数
+	SetErrorHandler(handler ErrorHandler)
+
 	// AddRouter 添加新的路由信息
 	AddRouter(router *Router)
 
@@ -108,20 +138,36 @@ type BaseWebContainer struct {
 	enableSwag bool     // 是否启用 Swagger 功能
 	swagger    *Swagger // 和容器绑定的 Swagger 对象
 
+	swagMutex sync.Mutex
+	swagPaths map[string]spec.PathItem // 用户直接添加的 path，不包括路由生成的部分
+	swagDoc   string                   // 缓存的 Swagger 文档
+	swagDirty bool                     // 路由发生变化，需要重新生成 Swagger 文档
+
 	filters        []Filter // 其他过滤器
 	loggerFilter   Filter   // 日志过滤器
 	recoveryFilter Filter   // 恢复过滤器
+
+	errorHandler ErrorHandler // 错误处理函数
 }
 
 // NewBaseWebContainer BaseWebContainer 的构造函数
 func NewBaseWebContainer(config ContainerConfig) *BaseWebContainer {
-	return &BaseWebContainer{
+	c := &BaseWebContainer{
 		WebMapping:     NewDefaultWebMapping(),
 		config:         config,
 		enableSwag:     true,
 		loggerFilter:   defaultLoggerFilter,
 		recoveryFilter: defaultRecoveryFilter,
+		errorHandler:   DefaultErrorHandler,
 	}
+
+	// 路由发生变化之后在下次访问时重新生成 Swagger 文档，只在这里注册一次
+	c.OnChange(func() {
+		c.swagMutex.Lock()
+		c.swagDirty = true
+		c.swagMutex.Unlock()
+	})
+	return c
 }
 
 // Address 返回监听地址
@@ -169,6 +215,19 @@ func (c *BaseWebContainer) SetRecoveryFilter(filter Filter) {
 	c.recoveryFilter = filter
 }
 
+// GetErrorHandler 获取错误处理函数
+func (c *BaseWebContainer) GetErrorHandler() ErrorHandler {
+	return c.errorHandler
+}
+
+// SetErrorHandler 设置错误处理函数，为 nil 时使用默认的错误处理函数
+func (c *BaseWebContainer) SetErrorHandler(handler ErrorHandler) {
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	c.errorHandler = handler
+}
+
 // AddRouter 添加新的路由信息
 func (c *BaseWebContainer) AddRouter(router *Router) {
 	for _, mapper := range router.mapping.Mappers() {
@@ -199,24 +258,25 @@ func (c *BaseWebContainer) PreStart() {
 
 	if c.enableSwag && c.swagger != nil {
 
-		// 注册 path 的 Operation
-		for _, mapper := range c.Mappers() {
-			if op := mapper.swagger; op != nil {
-				if err := op.parseBind(); err != nil {
-					panic(err)
-				}
-				c.swagger.AddPath(mapper.Path(), mapper.Method(), op)
-			}
+		// 保存用户直接添加的 path，每次重新生成文档时以此为基础
+		c.swagPaths = make(map[string]spec.PathItem)
+		for path, item := range c.swagger.Paths.Paths {
+			c.swagPaths[path] = item
 		}
 
-		doc := c.swagger.ReadDoc()
+		// 立即生成一次，以便尽早发现错误
+		c.swagMutex.Lock()
+		c.swagDirty = true
+		c.swagMutex.Unlock()
+		c.swaggerDoc()
+
 		hSwagger := httpSwagger.Handler(httpSwagger.URL("/swagger/doc.json"))
 
 		// 注册 swagger-ui 和 doc.json 接口
 		c.GetMapping("/swagger/*", func(webCtx WebContext) {
 			if webCtx.PathParam("*") == "doc.json" {
 				webCtx.Header(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
-				webCtx.String(http.StatusOK, doc)
+				webCtx.String(http.StatusOK, c.swaggerDoc())
 			} else {
 				hSwagger(webCtx.ResponseWriter(), webCtx.Request())
 			}
@@ -228,17 +288,90 @@ func (c *BaseWebContainer) PreStart() {
 
 }
 
+// swaggerDoc 返回 Swagger 文档，路由发生变化之后重新生成。应当在调用
+// AddMapper 之前设置好 Mapper 的 Swagger 文档，否则可能不会被重新生成。
+func (c *BaseWebContainer) swaggerDoc() string {
+	c.swagMutex.Lock()
+	defer c.swagMutex.Unlock()
+
+	if c.swagDirty {
+		paths := make(map[string]spec.PathItem, len(c.swagPaths))
+		for path, item := range c.swagPaths {
+			paths[path] = item
+		}
+		c.swagger.Paths.Paths = paths
+
+		// 注册过滤器的认证方式等信息
+		registerSwaggerFilters(c.swagger, c.filters)
+
+		// 注册 path 的 Operation
+		for _, mapper := range sortedMappers(c.Mappers()) {
+			registerSwaggerFilters(c.swagger, mapper.filters)
+			if op := mapper.swagger; op != nil {
+				if err := op.parseBind(); err != nil {
+					panic(err)
+				}
+				op.parsePathParams(mapper.Path())
+				if mapper.isSecured() {
+					c.securedWith(op, mapper)
+				}
+				c.swagger.AddPath(mapper.Path(), mapper.Method(), op)
+			}
+		}
+
+		c.swagDoc = c.swagger.ReadDoc()
+		c.swagDirty = false
+	}
+	return c.swagDoc
+}
+
+// securedWith 把路由声明的认证要求添加到 Operation 的 security，认证方式为路由
+// 和容器的过滤器注册的认证方式，没有时使用文档中所有的认证方式。oauth2 认证方式
+// 使用路由声明的角色和权限作为 scope。
+func (c *BaseWebContainer) securedWith(op *Operation, mapper *Mapper) {
+
+	s := NewSwagger()
+	registerSwaggerFilters(s, c.filters)
+	registerSwaggerFilters(s, mapper.filters)
+	definitions := s.SecurityDefinitions
+	if len(definitions) == 0 {
+		definitions = c.swagger.SecurityDefinitions
+	}
+
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Swagger 2.0 只有 oauth2 可以声明 scope，其他认证方式的 scope 必须为空数组
+	scopes := append(append([]string(nil), mapper.GetRoles()...), mapper.GetPermissions()...)
+	for _, name := range names {
+		if op.hasSecurity(name) {
+			continue
+		}
+		if definitions[name].Type == "oauth2" {
+			op.SecuredWith(name, scopes...)
+		} else {
+			op.SecuredWith(name, []string{}...)
+		}
+	}
+}
+
 // PrintMapper 打印路由注册信息
 func (c *BaseWebContainer) PrintMapper(m *Mapper) {
 	file, line, fnName := m.handler.FileLine()
-	SpringLogger.Infof("%v :%d %s -> %s:%d %s", GetMethod(m.method), c.config.Port, m.path, file, line, fnName)
+	SpringLogger.Infof("%v %s:%d %s -> %s:%d %s", GetMethod(m.method), m.host, c.config.Port, m.path, file, line, fnName)
 }
 
 /////////////////// Invoke Handler //////////////////////
 
-// InvokeHandler 执行 Web 处理函数
+// InvokeHandler 执行 Web 处理函数，启用 TraceFilter 时记录每个过滤器的耗时
 func InvokeHandler(ctx WebContext, fn Handler, filters []Filter) {
-	if len(filters) > 0 {
+	if trace := GetChainTrace(ctx); trace != nil {
+		filters = append(filters, HandlerFilter(fn))
+		newTracedFilterChain(filters, trace).Next(ctx)
+	} else if len(filters) > 0 {
 		filters = append(filters, HandlerFilter(fn))
 		chain := NewDefaultFilterChain(filters)
 		chain.Next(ctx)
@@ -293,28 +426,21 @@ func WrapH(h http.Handler) Handler {
 
 /////////////////// Web Filters //////////////////////
 
-var defaultRecoveryFilter = &recoveryFilter{}
-
-// recoveryFilter 恢复过滤器
-type recoveryFilter struct{}
-
-func (f *recoveryFilter) Invoke(ctx WebContext, chain FilterChain) {
-
-	defer func() {
-		if err := recover(); err != nil {
-			ctx.LogError("[PANIC RECOVER] ", err)
-			ctx.Status(http.StatusInternalServerError)
-		}
-	}()
-
-	chain.Next(ctx)
-}
-
 var defaultLoggerFilter = &loggerFilter{}
 
 // loggerFilter 日志过滤器
 type loggerFilter struct{}
 
+// Phase 日志过滤器在路由匹配之前执行
+func (f *loggerFilter) Phase() FilterPhase {
+	return PreRoutingPhase
+}
+
+// Order 日志过滤器位于所有过滤器的最前面
+func (f *loggerFilter) Order() int {
+	return LoggerFilterOrder
+}
+
 func (f *loggerFilter) Invoke(ctx WebContext, chain FilterChain) {
 	start := time.Now()
 	chain.Next(ctx)
@@ -17,6 +17,7 @@
 package SpringWeb
 
 import (
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -28,6 +29,46 @@ import (
 // WebContextKey WebContext 和 NativeContext 相互转换的 Key
 const WebContextKey = "@WebCtx"
 
+// WebMappingKey WebContext 中保存当前容器路由表的 Key
+const WebMappingKey = "@WebMapping"
+
+// MapperKey WebContext 中保存匹配到的路由 (*Mapper) 的 Key
+const MapperKey = "@Mapper"
+
+// GetMapper 返回请求匹配到的路由，没有匹配到路由时返回 nil
+func GetMapper(ctx WebContext) *Mapper {
+	m, _ := ctx.Get(MapperKey).(*Mapper)
+	return m
+}
+
+// ResponseWriterSetter 可以替换 http.ResponseWriter 的 WebContext，过滤器
+// 使用它包装响应，并且应当在返回时恢复原来的 ResponseWriter。WebContext 的
+// 实现不一定支持，使用前需要进行类型断言。
+type ResponseWriterSetter interface {
+	SetResponseWriter(w http.ResponseWriter)
+}
+
+// HostParamsKey WebContext 中保存 Host 路由参数 (*RouteParams) 的 Key
+const HostParamsKey = "@HostParams"
+
+// HostParam 返回 Host 路由中的参数值，例如 {tenant}.example.com 中的 tenant，
+// * 返回通配符匹配的部分。请求没有匹配到 Host 路由时返回空字符串。
+func HostParam(ctx WebContext, name string) string {
+	if params, ok := ctx.Get(HostParamsKey).(*RouteParams); ok {
+		return params.Get(name)
+	}
+	return ""
+}
+
+// URLFor 使用当前容器的路由表生成 URL 地址，需要容器在 WebContext 中保存
+// WebMappingKey 对应的路由表。
+func URLFor(ctx WebContext, name string, params ...interface{}) (string, error) {
+	if mapping, ok := ctx.Get(WebMappingKey).(WebMapping); ok {
+		return mapping.URLFor(name, params...)
+	}
+	return "", errors.New("no WebMapping found in the WebContext")
+}
+
 // WebContext 上下文接口，设计理念：为社区中优秀的 Web 服务器提供一个抽象层，
 // 使得底层可以灵活切换，因此在功能上取这些 Web 服务器功能的交集，同时提供获取
 // 底层对象的接口，以便在不能满足用户要求的时候使用底层实现的能力，当然要慎用。
@@ -68,7 +109,8 @@ type WebContext interface {
 	// ClientIP implements a best effort algorithm to return the real client IP,
 	// it parses X-Real-IP and X-Forwarded-For in order to work properly with
 	// reverse-proxies such us: nginx or haproxy. Use X-Forwarded-For before
-	// X-Real-Ip as nginx uses X-Real-Ip with the proxy's IP.
+	// X-Real-Ip as nginx uses X-Real-Ip with the proxy's IP. The headers are
+	// used only when the request comes from a trusted proxy.
 	ClientIP() string
 
 	// Path returns the registered path for the handler.
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig 跨域资源共享的配置
+type CORSConfig struct {
+	AllowOrigins       []string // 允许的源，支持精确匹配、* 和 https://*.example.com 形式的通配符
+	AllowOriginRegexps []string // 允许的源的正则表达式，需要完整匹配，不区分大小写
+	AllowMethods       []string // 允许的方法，为空时使用路径注册的所有方法
+	AllowHeaders       []string // 允许的请求头，为空时允许预检请求中的所有请求头
+	ExposeHeaders      []string // 允许浏览器读取的响应头
+	AllowCredentials   bool     // 是否允许携带 Cookie 等凭证，不能和 * 源同时使用
+	MaxAge             int      // 预检结果的缓存秒数，为 0 时不设置
+}
+
+// CORSFilter 跨域资源共享过滤器，在路由匹配之后执行，这样没有注册 OPTIONS 方法的
+// 路径也能响应预检请求，并且可以从路由表中得到路径允许的方法。
+type CORSFilter struct {
+	config       CORSConfig
+	allowAll     bool             // 允许所有源
+	origins      map[string]bool  // 精确匹配的源
+	patterns     []*regexp.Regexp // 通配符和正则表达式形式的源
+	allowMethods string
+	allowHeaders string
+	exposeHeader string
+	maxAge       string
+}
+
+// NewCORSFilter CORSFilter 的构造函数，正则表达式不合法时 panic。允许 * 源的
+// 同时允许携带凭证会让任意网站都能以用户的身份访问接口，因此也会 panic。
+func NewCORSFilter(config CORSConfig) *CORSFilter {
+
+	f := &CORSFilter{
+		config:       config,
+		origins:      make(map[string]bool),
+		allowMethods: strings.Join(config.AllowMethods, ", "),
+		allowHeaders: strings.Join(config.AllowHeaders, ", "),
+		exposeHeader: strings.Join(config.ExposeHeaders, ", "),
+	}
+
+	if config.MaxAge > 0 {
+		f.maxAge = strconv.Itoa(config.MaxAge)
+	}
+
+	for _, origin := range config.AllowOrigins {
+		switch {
+		case origin == "*":
+			if config.AllowCredentials {
+				panic(errors.New("cors: AllowOrigins \"*\" can't be used with AllowCredentials"))
+			}
+			f.allowAll = true
+		case strings.Contains(origin, "*"):
+			expr := "^" + strings.Replace(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[^/]*`, -1) + "$"
+			f.patterns = append(f.patterns, regexp.MustCompile(expr))
+		default:
+			f.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	for _, expr := range config.AllowOriginRegexps {
+		f.patterns = append(f.patterns, regexp.MustCompile("(?i)^(?:"+expr+")$"))
+	}
+	return f
+}
+
+// AllowOrigin 返回是否允许该源
+func (f *CORSFilter) AllowOrigin(origin string) bool {
+	if f.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if f.origins[origin] {
+		return true
+	}
+	for _, p := range f.patterns {
+		if p.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *CORSFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		chain.Next(ctx)
+		return
+	}
+
+	h := ctx.ResponseWriter().Header()
+	h.Add(HeaderVary, HeaderOrigin)
+
+	if r.Method == http.MethodOptions && r.Header.Get(HeaderAccessControlRequestMethod) != "" {
+		h.Add(HeaderVary, HeaderAccessControlRequestMethod)
+		h.Add(HeaderVary, HeaderAccessControlRequestHeaders)
+		if f.preflight(ctx, origin) {
+			ctx.NoContent(http.StatusNoContent)
+			return
+		}
+		chain.Next(ctx)
+		return
+	}
+
+	if f.AllowOrigin(origin) {
+		f.setOrigin(ctx, origin)
+		if f.exposeHeader != "" {
+			ctx.Header(HeaderAccessControlExposeHeaders, f.exposeHeader)
+		}
+	}
+	chain.Next(ctx)
+}
+
+// preflight 响应预检请求，源或者方法不被允许时返回 false，交给后续的处理函数
+func (f *CORSFilter) preflight(ctx WebContext, origin string) bool {
+
+	if !f.AllowOrigin(origin) {
+		return false
+	}
+
+	r := ctx.Request()
+	method := r.Header.Get(HeaderAccessControlRequestMethod)
+
+	// 没有配置允许的方法时使用路径注册的所有方法
+	allowMethods := f.allowMethods
+	if allowMethods == "" {
+		allowed := AllowedMethods(ctx)
+		if allowed&methodBit(method) == 0 {
+			return false
+		}
+		allowMethods = allowHeader(allowed)
+	} else if !containsToken(f.config.AllowMethods, method) {
+		return false
+	}
+
+	f.setOrigin(ctx, origin)
+	ctx.Header(HeaderAccessControlAllowMethods, allowMethods)
+
+	if f.allowHeaders != "" {
+		ctx.Header(HeaderAccessControlAllowHeaders, f.allowHeaders)
+	} else if headers := r.Header.Get(HeaderAccessControlRequestHeaders); headers != "" {
+		ctx.Header(HeaderAccessControlAllowHeaders, headers)
+	}
+
+	if f.maxAge != "" {
+		ctx.Header(HeaderAccessControlMaxAge, f.maxAge)
+	}
+	return true
+}
+
+// setOrigin 设置 Access-Control-Allow-Origin 和 Access-Control-Allow-Credentials
+func (f *CORSFilter) setOrigin(ctx WebContext, origin string) {
+	if f.allowAll {
+		ctx.Header(HeaderAccessControlAllowOrigin, "*")
+	} else {
+		ctx.Header(HeaderAccessControlAllowOrigin, origin)
+	}
+	if f.config.AllowCredentials {
+		ctx.Header(HeaderAccessControlAllowCredentials, "true")
+	}
+}
+
+// containsToken 返回 tokens 中是否包含 s，不区分大小写
+func containsToken(tokens []string, s string) bool {
+	for _, t := range tokens {
+		if strings.EqualFold(t, s) {
+			return true
+		}
+	}
+	return false
+}
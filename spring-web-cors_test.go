@@ -0,0 +1,129 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCORSFilter_AllowOrigin(t *testing.T) {
+
+	f := SpringWeb.NewCORSFilter(SpringWeb.CORSConfig{
+		AllowOrigins:       []string{"https://app.example.com", "https://*.example.org", "https://*.Example.net"},
+		AllowOriginRegexps: []string{`http://localhost:\d+`, `https://API\.example\.io`},
+	})
+
+	for origin, ok := range map[string]bool{
+		"https://app.example.com":    true,
+		"https://APP.example.com":    true,
+		"https://x.example.com":      false,
+		"https://a.example.org":      true,
+		"https://example.org":        false,
+		"https://a.example.org.cn":   false,
+		"http://localhost:3000":      true,
+		"http://localhost:3000.evil": false,
+		"https://a.example.net":      true,
+		"https://A.EXAMPLE.NET":      true,
+		"https://api.example.io":     true,
+	} {
+		assert.Equal(t, f.AllowOrigin(origin), ok, origin)
+	}
+
+	all := SpringWeb.NewCORSFilter(SpringWeb.CORSConfig{AllowOrigins: []string{"*"}})
+	assert.Equal(t, all.AllowOrigin("https://any.com"), true)
+
+	// * 不能和 AllowCredentials 同时使用
+	defer func() {
+		assert.Equal(t, recover() != nil, true)
+	}()
+	SpringWeb.NewCORSFilter(SpringWeb.CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	t.Fatal("should panic")
+}
+
+func TestCORSFilter(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1", ImplicitHead: true})
+	c.AddFilter(SpringWeb.NewCORSFilter(SpringWeb.CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+
+	ok := func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	}
+	c.GetMapping("/user/:id", ok)
+	c.PutMapping("/user/:id", ok)
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address() + "/user/1"
+	origin := "https://app.example.com"
+
+	t.Run("preflight", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodOptions, url, "",
+			"Origin", origin,
+			"Access-Control-Request-Method", "PUT",
+			"Access-Control-Request-Headers", "X-Token")
+		assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), origin)
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Methods"), "GET, HEAD, PUT")
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Headers"), "X-Token")
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Credentials"), "true")
+		assert.Equal(t, resp.Header.Get("Access-Control-Max-Age"), "600")
+		assert.Equal(t, resp.Header["Vary"], []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"})
+	})
+
+	t.Run("preflight method not allowed", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodOptions, url, "",
+			"Origin", origin,
+			"Access-Control-Request-Method", "DELETE")
+		assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "")
+		assert.Equal(t, resp.Header.Get("Allow"), "GET, HEAD, PUT, OPTIONS")
+	})
+
+	t.Run("preflight origin not allowed", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodOptions, url, "",
+			"Origin", "https://evil.com",
+			"Access-Control-Request-Method", "PUT")
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "")
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Methods"), "")
+	})
+
+	t.Run("actual", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodPut, url, "", "Origin", origin)
+		assert.Equal(t, body, "ok")
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), origin)
+		assert.Equal(t, resp.Header.Get("Access-Control-Expose-Headers"), "X-Total")
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Credentials"), "true")
+
+		resp, _ = doRequest(t, http.MethodGet, url, "", "Origin", "https://evil.com")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "")
+
+		resp, _ = doRequest(t, http.MethodGet, url, "")
+		assert.Equal(t, resp.Header.Get("Vary"), "")
+	})
+}
@@ -0,0 +1,353 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrincipalKey WebContext 中保存认证通过的用户的 Key
+const PrincipalKey = "@Principal"
+
+// Principal 认证通过的用户
+type Principal struct {
+	Name  string   // 用户名，API Key 认证时为 Key 的所有者
+	Roles []string // 用户拥有的角色
+}
+
+// HasRole 返回用户是否拥有该角色
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPrincipal 返回认证通过的用户，没有认证时返回 nil
+func GetPrincipal(ctx WebContext) *Principal {
+	p, _ := ctx.Get(PrincipalKey).(*Principal)
+	return p
+}
+
+// CredentialStore 保存用户凭证的接口，实现时应当使用常量时间的比较方式
+type CredentialStore interface {
+
+	// Authenticate 校验用户名和密码，成功时返回对应的用户
+	Authenticate(username string, password string) (*Principal, bool)
+
+	// AuthenticateKey 校验 API Key，成功时返回对应的用户
+	AuthenticateKey(key string) (*Principal, bool)
+}
+
+// DefaultPasswordIterations 密码哈希默认的 PBKDF2 迭代次数，可以根据服务器的性能
+// 调整，修改之后只影响新计算的哈希。每次校验密码都需要完整计算一次哈希 (默认
+// 迭代次数下约 150ms 的 CPU 时间)，用户不存在时也是如此，所以大量错误的 Basic
+// 认证请求可以耗尽服务器的 CPU，对外暴露时应当配合限流。
+var DefaultPasswordIterations = 600000
+
+// maxVerifiedCredentials 每个 MemoryCredentialStore 缓存的校验成功的凭证的最大数量
+const maxVerifiedCredentials = 1024
+
+// verifiedSecret 计算缓存 Key 的随机密钥，只在当前进程内有效
+var verifiedSecret = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// passwordHashPrefix HashPassword 生成的密码哈希的前缀
+const passwordHashPrefix = "pbkdf2-sha256$"
+
+// credential 保存的凭证，API Key 只保存 SHA-256 摘要，密码只保存加盐的 PBKDF2 哈希
+type credential struct {
+	digest     [sha256.Size]byte
+	salt       []byte // 密码的盐
+	iterations int    // 密码的迭代次数
+	principal  *Principal
+}
+
+// MemoryCredentialStore 基于内存的 CredentialStore，并发安全。为了避免合法用户的
+// 每个请求都重新计算 PBKDF2，校验成功的用户名和密码会以 HMAC 摘要的形式缓存在内存
+// 中 (密钥为进程内的随机数)，缓存的数量有上限。校验失败的请求不会被缓存，因此缓存
+// 并不能防御使用错误密码的 DoS 攻击。
+type MemoryCredentialStore struct {
+	mutex    sync.RWMutex
+	users    map[string]*credential
+	keys     map[[sha256.Size]byte]*credential
+	verified map[[sha256.Size]byte]*credential
+}
+
+// NewMemoryCredentialStore MemoryCredentialStore 的构造函数
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{
+		users:    make(map[string]*credential),
+		keys:     make(map[[sha256.Size]byte]*credential),
+		verified: make(map[[sha256.Size]byte]*credential),
+	}
+}
+
+// AddUser 添加用户，password 可以是明文，也可以是 HashPassword 生成的哈希。明文
+// 密码使用随机盐和 DefaultPasswordIterations 计算哈希之后保存。
+func (s *MemoryCredentialStore) AddUser(username string, password string, roles ...string) error {
+	c, err := parsePassword(password)
+	if err != nil {
+		return err
+	}
+	c.principal = &Principal{Name: username, Roles: roles}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.users[username] = c
+	return nil
+}
+
+// AddKey 添加 API Key，owner 为 Key 的所有者。API Key 应当是足够长的随机字符串，
+// 因此只保存 SHA-256 摘要。
+func (s *MemoryCredentialStore) AddKey(key string, owner string, roles ...string) {
+	digest := sha256.Sum256([]byte(key))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys[digest] = &credential{digest: digest, principal: &Principal{Name: owner, Roles: roles}}
+}
+
+// dummySalt 用户不存在时使用的盐，使用默认的迭代次数参与比较，使两种情况的耗时相同
+var dummySalt = make([]byte, 16)
+
+func (s *MemoryCredentialStore) Authenticate(username string, password string) (*Principal, bool) {
+	key := verifiedKey(username, password)
+	s.mutex.RLock()
+	c, ok := s.users[username]
+	cached := ok && s.verified[key] == c
+	s.mutex.RUnlock()
+	if cached {
+		return c.principal, true
+	}
+	if !ok {
+		c = &credential{salt: dummySalt, iterations: DefaultPasswordIterations}
+	}
+	digest := pbkdf2([]byte(password), c.salt, c.iterations)
+	if subtle.ConstantTimeCompare(digest, c.digest[:]) == 1 && ok {
+		s.cacheVerified(key, c)
+		return c.principal, true
+	}
+	return nil, false
+}
+
+// cacheVerified 缓存校验成功的凭证，缓存已满时随机淘汰一个。用户被 AddUser 替换
+// 之后缓存的凭证和当前凭证不同，自然失效。
+func (s *MemoryCredentialStore) cacheVerified(key [sha256.Size]byte, c *credential) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.verified) >= maxVerifiedCredentials {
+		for k := range s.verified {
+			delete(s.verified, k)
+			break
+		}
+	}
+	s.verified[key] = c
+}
+
+// verifiedKey 返回用户名和密码的缓存 Key
+func verifiedKey(username string, password string) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, verifiedSecret)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	var key [sha256.Size]byte
+	copy(key[:], mac.Sum(nil))
+	return key
+}
+
+func (s *MemoryCredentialStore) AuthenticateKey(key string) (*Principal, bool) {
+	digest := sha256.Sum256([]byte(key))
+	s.mutex.RLock()
+	c, ok := s.keys[digest]
+	s.mutex.RUnlock()
+	if !ok {
+		c = &credential{}
+	}
+	if subtle.ConstantTimeCompare(digest[:], c.digest[:]) == 1 && ok {
+		return c.principal, true
+	}
+	return nil, false
+}
+
+// HashPassword 使用随机盐和 PBKDF2-HMAC-SHA256 计算密码的哈希，返回
+// pbkdf2-sha256$<iterations>$<salt>$<hash> 格式的字符串，盐和哈希使用不带填充的
+// base64 编码。iterations 不大于 0 时使用 DefaultPasswordIterations。
+func HashPassword(password string, iterations int) string {
+	if iterations <= 0 {
+		iterations = DefaultPasswordIterations
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	digest := pbkdf2([]byte(password), salt, iterations)
+	return fmt.Sprintf("%s%d$%s$%s", passwordHashPrefix, iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(digest))
+}
+
+// parsePassword 解析明文密码或者 HashPassword 生成的哈希
+func parsePassword(password string) (*credential, error) {
+
+	if strings.HasPrefix(password, "sha256:") {
+		return nil, errors.New("unsalted sha256 password digest is not supported, use HashPassword instead")
+	}
+
+	if !strings.HasPrefix(password, passwordHashPrefix) {
+		password = HashPassword(password, DefaultPasswordIterations)
+	}
+
+	invalid := fmt.Errorf("invalid password hash %q", password)
+	fields := strings.Split(strings.TrimPrefix(password, passwordHashPrefix), "$")
+	if len(fields) != 3 {
+		return nil, invalid
+	}
+	iterations, err := strconv.Atoi(fields[0])
+	if err != nil || iterations <= 0 {
+		return nil, invalid
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[1])
+	if err != nil || len(salt) == 0 {
+		return nil, invalid
+	}
+	digest, err := base64.RawStdEncoding.DecodeString(fields[2])
+	if err != nil || len(digest) != sha256.Size {
+		return nil, invalid
+	}
+
+	c := &credential{salt: salt, iterations: iterations}
+	copy(c.digest[:], digest)
+	return c, nil
+}
+
+// pbkdf2 使用 HMAC-SHA256 作为伪随机函数的 PBKDF2 (RFC 8018)，只生成一个块
+func pbkdf2(password []byte, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, password)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(nil)
+	t := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range t {
+			t[j] ^= u[j]
+		}
+	}
+	return t
+}
+
+// FileCredentialStore 从文件中加载凭证的 CredentialStore，可以通过 Reload 重新加载。
+// 文件每行一条凭证，# 开头的行为注释，字段之间使用空白分隔，角色之间使用逗号分隔：
+//
+//	basic <username> <password> [roles]
+//	apikey <key> <owner> [roles]
+//
+// 密码可以是明文，也可以是 HashPassword 生成的 pbkdf2-sha256$ 开头的哈希，不应当
+// 在文件中保存明文密码。
+type FileCredentialStore struct {
+	file  string
+	mutex sync.RWMutex
+	store *MemoryCredentialStore
+}
+
+// NewFileCredentialStore FileCredentialStore 的构造函数，立即加载文件
+func NewFileCredentialStore(file string) (*FileCredentialStore, error) {
+	s := &FileCredentialStore{file: file}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload 重新加载凭证文件，加载失败时继续使用原来的凭证
+func (s *FileCredentialStore) Reload() error {
+
+	f, err := os.Open(s.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	store := NewMemoryCredentialStore()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || len(fields) > 4 {
+			return fmt.Errorf("%s:%d: invalid credential", s.file, n)
+		}
+		var roles []string
+		if len(fields) == 4 {
+			roles = strings.Split(fields[3], ",")
+		}
+		switch fields[0] {
+		case "basic":
+			if err = store.AddUser(fields[1], fields[2], roles...); err != nil {
+				return fmt.Errorf("%s:%d: %v", s.file, n, err)
+			}
+		case "apikey":
+			store.AddKey(fields[1], fields[2], roles...)
+		default:
+			return fmt.Errorf("%s:%d: unknown credential type %q", s.file, n, fields[0])
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.store = store
+	s.mutex.Unlock()
+	return nil
+}
+
+// current 返回当前使用的凭证
+func (s *FileCredentialStore) current() *MemoryCredentialStore {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.store
+}
+
+func (s *FileCredentialStore) Authenticate(username string, password string) (*Principal, bool) {
+	return s.current().Authenticate(username, password)
+}
+
+func (s *FileCredentialStore) AuthenticateKey(key string) (*Principal, bool) {
+	return s.current().AuthenticateKey(key)
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-spring/go-spring-error"
+	"github.com/go-spring/go-spring-utils"
+)
+
+// ErrorKey WebContext 中保存尚未处理的错误的 Key
+const ErrorKey = "@Error"
+
+// SetError 保存尚未处理的错误，err 为 nil 时清除错误
+func SetError(ctx WebContext, err error) {
+	ctx.Set(ErrorKey, err)
+}
+
+// GetError 返回尚未处理的错误
+func GetError(ctx WebContext) error {
+	err, _ := ctx.Get(ErrorKey).(error)
+	return err
+}
+
+// HttpError 带有 HTTP 状态码的错误
+type HttpError struct {
+	Code     int    // HTTP 状态码
+	Message  string // 返回给客户端的错误信息
+	Internal error  // 原始错误，不会返回给客户端
+}
+
+// NewHttpError HttpError 的构造函数，message 为空时使用状态码对应的文本
+func NewHttpError(code int, message ...string) *HttpError {
+	e := &HttpError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
+}
+
+// WrapHttpError 使用 err 的错误信息创建 HttpError
+func WrapHttpError(code int, err error) *HttpError {
+	return &HttpError{Code: code, Message: err.Error(), Internal: err}
+}
+
+func (e *HttpError) Error() string {
+	return e.Message
+}
+
+// Unwrap 返回原始错误
+func (e *HttpError) Unwrap() error {
+	return e.Internal
+}
+
+// RpcResultError 把 SpringError.RpcResult 包装成 error，默认的错误处理函数
+// 以 JSON 格式返回其中的 RpcResult，和 BIND 形式的处理函数保持一致。
+type RpcResultError struct {
+	Result *SpringError.RpcResult
+}
+
+// NewRpcResultError RpcResultError 的构造函数
+func NewRpcResultError(result *SpringError.RpcResult) *RpcResultError {
+	return &RpcResultError{Result: result}
+}
+
+func (e *RpcResultError) Error() string {
+	if e.Result.Err != "" {
+		return e.Result.Msg + ": " + e.Result.Err
+	}
+	return e.Result.Msg
+}
+
+// ErrorHandler 容器级别的错误处理函数，把错误转换成 HTTP 响应
+type ErrorHandler func(ctx WebContext, err error)
+
+// DefaultErrorHandler 默认的错误处理函数：RpcResultError 返回 200 和 JSON 格式的
+// RpcResult，校验错误返回 400，HttpError 返回其状态码，其他错误返回 500 并且不
+// 暴露错误信息。包装过的错误沿着 Unwrap 或者 Cause 方法查找以上错误。响应已经
+// 写出时只打印日志。
+func DefaultErrorHandler(ctx WebContext, err error) {
+
+	if w, ok := ctx.ResponseWriter().(ResponseWriter); ok && w.Written() {
+		ctx.LogError("response already written, error: ", err)
+		return
+	}
+
+	switch e := findError(err).(type) {
+	case *RpcResultError:
+		ctx.JSON(http.StatusOK, e.Result)
+	case validator.ValidationErrors:
+		ctx.String(http.StatusBadRequest, "%s", e.Error())
+	case *HttpError:
+		if e.Internal != nil {
+			ctx.LogError(e.Message, ": ", e.Internal)
+		}
+		ctx.String(e.Code, "%s", e.Message)
+	default:
+		ctx.LogError(err)
+		code := http.StatusInternalServerError
+		ctx.String(code, "%s", http.StatusText(code))
+	}
+}
+
+// findError 沿着错误链查找默认的错误处理函数能够识别的错误，错误链通过 Unwrap
+// 或者 Cause 方法连接，没有找到时返回 err 本身
+func findError(err error) error {
+	for e := err; e != nil; {
+		switch e.(type) {
+		case *RpcResultError, validator.ValidationErrors, *HttpError:
+			return e
+		}
+		switch v := e.(type) {
+		case interface{ Unwrap() error }:
+			e = v.Unwrap()
+		case interface{ Cause() error }:
+			e = v.Cause()
+		default:
+			e = nil
+		}
+	}
+	return err
+}
+
+// EFilterChain 返回 error 的过滤器链条
+type EFilterChain interface {
+	// Next 执行后续的过滤器和处理函数，返回它们产生的错误
+	Next(ctx WebContext) error
+}
+
+// EFilter 返回 error 的过滤器，通过 EFILTER 转换成 Filter。返回的错误先交给
+// 外层的 EFilter，最终由容器的 ErrorHandler 处理。
+type EFilter interface {
+	Invoke(ctx WebContext, chain EFilterChain) error
+}
+
+// EHandlerFunc 返回 error 的 Web 处理函数
+type EHandlerFunc func(WebContext) error
+
+// eFilter 把 EFilter 适配成 Filter
+type eFilter struct {
+	f EFilter
+}
+
+// EFILTER 把返回 error 的过滤器转换成 Filter
+func EFILTER(f EFilter) Filter {
+	return &eFilter{f: f}
+}
+
+func (f *eFilter) Invoke(ctx WebContext, chain FilterChain) {
+	if err := f.f.Invoke(ctx, eFilterChain{chain}); err != nil {
+		SetError(ctx, err)
+	}
+}
+
+// Phase 使用被包装的过滤器的执行阶段，规则和没有包装的过滤器相同
+func (f *eFilter) Phase() FilterPhase {
+	return filterPhase(f.f)
+}
+
+// Order 使用被包装的过滤器的顺序值，规则和没有包装的过滤器相同
+func (f *eFilter) Order() int {
+	return filterOrder(f.f)
+}
+
+// RegisterSwagger 使用被包装的过滤器注册 Swagger 文档
+func (f *eFilter) RegisterSwagger(s *Swagger) {
+	if a, ok := f.f.(SwaggerAware); ok {
+		a.RegisterSwagger(s)
+	}
+}
+
+// name 返回被包装的过滤器的类型
+func (f *eFilter) name() string {
+	return fmt.Sprintf("%T", f.f)
+}
+
+// eFilterChain 把 FilterChain 适配成 EFilterChain
+type eFilterChain struct {
+	chain FilterChain
+}
+
+func (c eFilterChain) Next(ctx WebContext) error {
+	c.chain.Next(ctx)
+	err := GetError(ctx)
+	if err != nil {
+		SetError(ctx, nil)
+	}
+	return err
+}
+
+// eHandler 返回 error 的 Web 处理函数
+type eHandler EHandlerFunc
+
+func (h eHandler) Invoke(ctx WebContext) {
+	if err := h(ctx); err != nil {
+		SetError(ctx, err)
+	}
+}
+
+func (h eHandler) FileLine() (file string, line int, fnName string) {
+	return SpringUtils.FileLine(h)
+}
+
+// EFUNC 把返回 error 的 Web 处理函数转换成 Handler
+func EFUNC(fn EHandlerFunc) Handler {
+	return eHandler(fn)
+}
@@ -16,12 +16,129 @@
 
 package SpringWeb
 
+import (
+	"sort"
+)
+
 // Filter 过滤器接口
 type Filter interface {
 	// Invoke 通过 chain.Next() 驱动链条向后执行
 	Invoke(ctx WebContext, chain FilterChain)
 }
 
+// FilterPhase 过滤器的执行阶段
+type FilterPhase int
+
+const (
+	PreRoutingPhase  = FilterPhase(-1) // 路由匹配之前执行，可以修改请求路径，只对容器级别的过滤器有效
+	PostRoutingPhase = FilterPhase(0)  // 路由匹配之后执行，默认阶段
+	PostHandlerPhase = FilterPhase(1)  // 在其他过滤器之后、紧挨着处理函数执行
+)
+
+// 预定义的过滤器顺序值
+const (
+	LoggerFilterOrder   = -2000
+	RecoveryFilterOrder = -1000
+)
+
+// OrderedFilter 声明了执行阶段和顺序的过滤器。过滤器先按照阶段排序，同一阶段
+// 内按照顺序值从小到大排序，顺序值相同时保持添加时的顺序。没有实现该接口的过滤器
+// 属于 PostRoutingPhase 阶段，顺序值为 0。路由级别的过滤器不能在路由匹配之前执行，
+// 其中 PreRoutingPhase 阶段的过滤器在路由匹配之后最先执行。
+type OrderedFilter interface {
+	Filter
+
+	// Phase 返回过滤器的执行阶段
+	Phase() FilterPhase
+
+	// Order 返回过滤器在执行阶段内的顺序值
+	Order() int
+}
+
+// orderedFilter 为过滤器指定执行阶段和顺序
+type orderedFilter struct {
+	Filter
+	phase FilterPhase
+	order int
+}
+
+// WithOrder 为过滤器指定执行阶段和顺序，用于无法修改源码的过滤器
+func WithOrder(filter Filter, phase FilterPhase, order int) Filter {
+	return &orderedFilter{Filter: filter, phase: phase, order: order}
+}
+
+// Unwrap 返回被包装的过滤器
+func (f *orderedFilter) Unwrap() Filter {
+	return f.Filter
+}
+
+func (f *orderedFilter) Phase() FilterPhase {
+	return f.phase
+}
+
+func (f *orderedFilter) Order() int {
+	return f.order
+}
+
+// FilterWrapper 包装了其他过滤器的过滤器，例如 WithOrder 和 ConditionalFilter。
+// 自定义的包装过滤器实现该接口之后，Swagger 文档、链路追踪和默认授权等功能能够找到
+// 被包装的过滤器。
+type FilterWrapper interface {
+	Filter
+
+	// Unwrap 返回被包装的过滤器
+	Unwrap() Filter
+}
+
+// unwrapFilter 返回 f 以及 f 逐层包装的过滤器，从外到内排列
+func unwrapFilter(f Filter) []Filter {
+	r := []Filter{f}
+	for {
+		w, ok := f.(FilterWrapper)
+		if !ok {
+			return r
+		}
+		f = w.Unwrap()
+		r = append(r, f)
+	}
+}
+
+// ordered 声明了执行阶段和顺序的对象，Filter 和 EFilter 使用相同的规则
+type ordered interface {
+	Phase() FilterPhase
+	Order() int
+}
+
+// filterPhase 返回过滤器的执行阶段，f 可以是 Filter 或者 EFilter
+func filterPhase(f interface{}) FilterPhase {
+	if o, ok := f.(ordered); ok {
+		return o.Phase()
+	}
+	return PostRoutingPhase
+}
+
+// filterOrder 返回过滤器的顺序值，f 可以是 Filter 或者 EFilter
+func filterOrder(f interface{}) int {
+	if o, ok := f.(ordered); ok {
+		return o.Order()
+	}
+	return 0
+}
+
+// SortFilters 返回按照执行阶段和顺序稳定排序之后的过滤器列表，不修改 filters，
+// 返回值的容量和长度相同，追加元素时不会修改共享的数组。
+func SortFilters(filters []Filter) []Filter {
+	r := make([]Filter, len(filters))
+	copy(r, filters)
+	sort.SliceStable(r, func(i, j int) bool {
+		if pi, pj := filterPhase(r[i]), filterPhase(r[j]); pi != pj {
+			return pi < pj
+		}
+		return filterOrder(r[i]) < filterOrder(r[j])
+	})
+	return r
+}
+
 // handlerFilter 包装 Web 处理接口的过滤器
 type handlerFilter struct {
 	fn Handler
@@ -36,6 +153,12 @@ func (h *handlerFilter) Invoke(ctx WebContext, _ FilterChain) {
 	h.fn.Invoke(ctx)
 }
 
+// name 返回被包装的处理函数的名称
+func (h *handlerFilter) name() string {
+	_, _, fnName := h.fn.FileLine()
+	return fnName
+}
+
 // FilterChain 过滤器链条接口
 type FilterChain interface {
 	Next(ctx WebContext)
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSortFilters(t *testing.T) {
+
+	a := &stringFilter{"a"}
+	b := SpringWeb.WithOrder(&stringFilter{"b"}, SpringWeb.PostHandlerPhase, -100)
+	c := SpringWeb.WithOrder(&stringFilter{"c"}, SpringWeb.PostRoutingPhase, 10)
+	d := SpringWeb.WithOrder(&stringFilter{"d"}, SpringWeb.PreRoutingPhase, 0)
+	e := &stringFilter{"e"}
+	f := SpringWeb.WithOrder(&stringFilter{"f"}, SpringWeb.PostRoutingPhase, -10)
+
+	filters := []SpringWeb.Filter{a, b, c, d, e, f}
+	sorted := SpringWeb.SortFilters(filters)
+	assert.Equal(t, sorted, []SpringWeb.Filter{d, f, a, e, c, b})
+	assert.Equal(t, cap(sorted), len(sorted))
+	assert.Equal(t, filters, []SpringWeb.Filter{a, b, c, d, e, f})
+
+	// EFILTER 包装的过滤器和没有包装的过滤器使用相同的规则
+	g := SpringWeb.EFILTER(&phaseEFilter{})
+	h := SpringWeb.EFILTER(&orderedEFilter{})
+	sorted = SpringWeb.SortFilters([]SpringWeb.Filter{a, g, h})
+	assert.Equal(t, sorted, []SpringWeb.Filter{h, a, g})
+}
+
+// phaseEFilter 只声明了执行阶段，没有声明顺序值，和没有实现 OrderedFilter 的过滤器一样
+type phaseEFilter struct{}
+
+func (f *phaseEFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.EFilterChain) error {
+	return chain.Next(ctx)
+}
+
+func (f *phaseEFilter) Phase() SpringWeb.FilterPhase {
+	return SpringWeb.PreRoutingPhase
+}
+
+// orderedEFilter 声明了执行阶段和顺序值
+type orderedEFilter struct {
+	phaseEFilter
+}
+
+func (f *orderedEFilter) Order() int {
+	return SpringWeb.LoggerFilterOrder
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Host 路由的语法，以 . 分隔的每一段称为 label：
+// api.example.com 只匹配该域名，不区分大小写；
+// {tenant}.example.com 中的 {tenant} 匹配一个 label，可以通过 HostParam 获取；
+// *.example.com 中的 * 只能出现在开头，匹配一个或者多个 label，名称为 *。
+// 匹配时忽略 Host 请求头中的端口号以及末尾的 .。
+
+const (
+	exactHost = iota
+	paramHost
+	wildCardHost
+)
+
+// hostPattern 解析后的 Host 路由
+type hostPattern struct {
+	pattern string
+	kind    int
+	labels  []hostLabel
+	names   []string // 参数名，和匹配时从右向左的顺序一致
+}
+
+// hostLabel Host 路由中的一段
+type hostLabel struct {
+	name  string
+	param bool
+}
+
+var (
+	hostPatternsMutex sync.Mutex
+	hostPatterns      = make(map[string]*hostPattern)
+)
+
+// getHostPattern 返回解析后的 Host 路由，格式错误时 panic
+func getHostPattern(pattern string) *hostPattern {
+	hostPatternsMutex.Lock()
+	defer hostPatternsMutex.Unlock()
+	if p, ok := hostPatterns[pattern]; ok {
+		return p
+	}
+	p := parseHostPattern(pattern)
+	hostPatterns[pattern] = p
+	return p
+}
+
+// parseHostPattern 解析 Host 路由
+func parseHostPattern(pattern string) *hostPattern {
+
+	p := &hostPattern{pattern: pattern, kind: exactHost}
+	if pattern == "" {
+		panic(fmt.Errorf("host pattern can't be empty"))
+	}
+
+	labels := strings.Split(strings.TrimSuffix(pattern, "."), ".")
+	for i, s := range labels {
+		switch {
+		case s == "":
+			panic(fmt.Errorf("host pattern %s has empty label", pattern))
+		case s == "*":
+			if i != 0 || len(labels) == 1 {
+				panic(fmt.Errorf("* should be the first label of host pattern %s", pattern))
+			}
+			p.kind = wildCardHost
+		case s[0] == '{' && s[len(s)-1] == '}':
+			name := s[1 : len(s)-1]
+			if name == "" || strings.ContainsAny(name, "{}:*") {
+				panic(fmt.Errorf("host pattern %s has bad param %s", pattern, s))
+			}
+			if p.kind == exactHost {
+				p.kind = paramHost
+			}
+			p.labels = append(p.labels, hostLabel{name: name, param: true})
+		default:
+			p.labels = append(p.labels, hostLabel{name: s})
+		}
+	}
+
+	for i := len(p.labels) - 1; i >= 0; i-- {
+		if p.labels[i].param {
+			p.names = append(p.names, p.labels[i].name)
+		}
+	}
+	if p.kind == wildCardHost {
+		p.names = append(p.names, "*")
+	}
+	return p
+}
+
+// shape 返回忽略参数名之后的 Host 路由，用于检测有歧义的路由
+func (p *hostPattern) shape() string {
+	var sb strings.Builder
+	if p.kind == wildCardHost {
+		sb.WriteString("*")
+	}
+	for _, l := range p.labels {
+		if sb.Len() > 0 {
+			sb.WriteString(".")
+		}
+		if l.param {
+			sb.WriteString("{}")
+		} else {
+			sb.WriteString(strings.ToLower(l.name))
+		}
+	}
+	return sb.String()
+}
+
+// less 返回 p 是否比 o 优先匹配：精确匹配优先于参数，参数优先于通配符，
+// 同类型的 label 多者优先。
+func (p *hostPattern) less(o *hostPattern) bool {
+	if p.kind != o.kind {
+		return p.kind < o.kind
+	}
+	if len(p.labels) != len(o.labels) {
+		return len(p.labels) > len(o.labels)
+	}
+	return p.pattern < o.pattern
+}
+
+// match 从右向左匹配 host 的每个 label，参数值追加到 values 中
+func (p *hostPattern) match(host string, values []string) ([]string, bool) {
+
+	host = stripHostPort(host)
+
+	for i := len(p.labels) - 1; i >= 0; i-- {
+		if host == "" {
+			return values, false
+		}
+		var label string
+		if j := strings.LastIndexByte(host, '.'); j >= 0 {
+			label, host = host[j+1:], host[:j]
+			if host == "" {
+				return values, false
+			}
+		} else {
+			label, host = host, ""
+		}
+		if label == "" {
+			return values, false
+		}
+		if l := p.labels[i]; l.param {
+			values = append(values, label)
+		} else if !strings.EqualFold(label, l.name) {
+			return values, false
+		}
+	}
+
+	if p.kind == wildCardHost {
+		if host == "" {
+			return values, false
+		}
+		return append(values, host), true
+	}
+	return values, host == ""
+}
+
+// stripHostPort 去掉 Host 中的端口号以及末尾的 .
+func stripHostPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
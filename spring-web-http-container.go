@@ -0,0 +1,496 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-spring/go-spring-logger"
+	"github.com/go-spring/go-spring-utils"
+)
+
+// HttpContainer 基于标准库 net/http 实现的 WebContainer，不依赖第三方 Web 框架。
+// 启动之后仍然可以添加、替换和删除路由，每次变化都会重新构建路由表并原子替换，
+// 正在处理的请求继续使用旧的路由表。
+type HttpContainer struct {
+	*BaseWebContainer
+
+	server     *http.Server
+	preFilters []Filter     // 路由匹配之前执行的容器级别过滤器，默认包括日志过滤器和恢复过滤器
+	filters    []Filter     // 路由匹配之后执行的容器级别过滤器
+	table      atomic.Value // 当前使用的路由表 *routeTable
+	mutex      sync.Mutex   // 保证路由表按照顺序重新构建
+	started    bool
+	pool       sync.Pool
+	proxies    []*net.IPNet // 可信的反向代理
+	addr       net.Addr     // 实际监听的地址
+}
+
+// NewHttpContainer HttpContainer 的构造函数
+func NewHttpContainer(config ContainerConfig) *HttpContainer {
+	c := &HttpContainer{BaseWebContainer: NewBaseWebContainer(config)}
+	c.proxies = parseTrustedProxies(config.TrustedProxies)
+	c.table.Store(&routeTable{tree: NewRouteTree(), routes: make(map[*Mapper][]Filter)})
+	c.pool.New = c.newContext
+	c.OnChange(c.refresh)
+	return c
+}
+
+// Start 启动 Web 容器，非阻塞
+func (c *HttpContainer) Start() {
+
+	c.PreStart()
+
+	// 日志过滤器和恢复过滤器默认在路由匹配之前最先执行
+	var filters []Filter
+	if f := c.GetLoggerFilter(); f != nil {
+		filters = append(filters, defaultOrder(f, PreRoutingPhase, LoggerFilterOrder))
+	}
+	if f := c.GetRecoveryFilter(); f != nil {
+		filters = append(filters, defaultOrder(f, PreRoutingPhase, RecoveryFilterOrder))
+	}
+
+	// 按照执行阶段和顺序排序，然后拆分为路由匹配之前和之后执行的两部分
+	filters = SortFilters(append(filters, c.GetFilters()...))
+	i := 0
+	for i < len(filters) && filterPhase(filters[i]) == PreRoutingPhase {
+		i++
+	}
+	c.preFilters, c.filters = filters[:i:i], filters[i:]
+
+	c.mutex.Lock()
+	c.started = true
+	c.table.Store(c.buildTable(true))
+	c.mutex.Unlock()
+
+	cfg := c.Config()
+	c.server = &http.Server{
+		Handler:      c,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+
+	// 同步监听端口，以便尽早发现端口被占用等错误
+	ln, err := net.Listen("tcp", c.BaseWebContainer.Address())
+	if err != nil {
+		panic(err)
+	}
+	c.addr = ln.Addr()
+
+	go func() {
+		if cfg.EnableSSL {
+			err = c.server.ServeTLS(ln, cfg.CertFile, cfg.KeyFile)
+		} else {
+			err = c.server.Serve(ln)
+		}
+		SpringLogger.Infof("exit http server on %s return %v", ln.Addr(), err)
+	}()
+}
+
+// Address 返回监听地址，启动之后返回实际监听的地址，例如端口为 0 时系统分配的端口
+func (c *HttpContainer) Address() string {
+	if c.addr != nil {
+		return c.addr.String()
+	}
+	return c.BaseWebContainer.Address()
+}
+
+// refresh 路由发生变化时重新构建路由表，容器启动之前什么也不做
+func (c *HttpContainer) refresh() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.started {
+		c.table.Store(c.buildTable(false))
+	}
+}
+
+// buildTable 使用当前的路由构建路由表，按照 Key 排序保证路由树的构建顺序是确定的
+func (c *HttpContainer) buildTable(print bool) *routeTable {
+
+	cfg := c.Config()
+	t := &routeTable{
+		tree:         NewRouteTree(),
+		routes:       make(map[*Mapper][]Filter),
+		guarded:      make(map[*Mapper][]Filter),
+		preFilters:   c.preFilters,
+		filters:      c.filters,
+		errorHandler: c.GetErrorHandler(),
+		implicitHead: cfg.ImplicitHead,
+		slashPolicy:  cfg.SlashPolicy,
+		proxies:      c.proxies,
+	}
+
+	for _, mapper := range sortedMappers(c.Mappers()) {
+		if print {
+			c.PrintMapper(mapper)
+		}
+		t.hostTree(mapper.Host()).Add(mapper)
+		filters := routeFilters(c.filters, mapper)
+		t.routes[mapper] = filters
+		if !hasAuthorizationFilter(filters) {
+			t.guarded[mapper] = SortFilters(append(filters, defaultAuthorizationFilter))
+		}
+	}
+
+	sort.Slice(t.hosts, func(i, j int) bool {
+		return t.hosts[i].pattern.less(t.hosts[j].pattern)
+	})
+
+	t.maxParams = t.tree.MaxParams()
+	for _, h := range t.hosts {
+		if n := h.tree.MaxParams(); n > t.maxParams {
+			t.maxParams = n
+		}
+		if n := len(h.pattern.names); n > t.maxHostParams {
+			t.maxHostParams = n
+		}
+	}
+	return t
+}
+
+// newContext 创建 httpContext 对象，预先分配路径参数的内存，避免请求时再分配
+func (c *HttpContainer) newContext() interface{} {
+	ctx := newHttpContext()
+	t := c.table.Load().(*routeTable)
+	ctx.params.Values = make([]string, 0, t.maxParams)
+	ctx.host.Values = make([]string, 0, t.maxHostParams)
+	return ctx
+}
+
+// parseTrustedProxies 解析可信的反向代理，单个 IP 转换成只包含该 IP 的网段，
+// 格式错误时 panic
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var r []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				panic(fmt.Errorf("invalid trusted proxy %q", proxy))
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			r = append(r, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(fmt.Errorf("invalid trusted proxy %q", proxy))
+		}
+		r = append(r, ipNet)
+	}
+	return r
+}
+
+// sortedMappers 返回按照 Key 排序的 Mapper 列表
+func sortedMappers(mappers map[string]*Mapper) []*Mapper {
+	keys := make([]string, 0, len(mappers))
+	for key := range mappers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	r := make([]*Mapper, 0, len(keys))
+	for _, key := range keys {
+		r = append(r, mappers[key])
+	}
+	return r
+}
+
+// defaultOrder 没有声明执行阶段和顺序的过滤器使用指定的默认值
+func defaultOrder(f Filter, phase FilterPhase, order int) Filter {
+	if _, ok := f.(OrderedFilter); ok {
+		return f
+	}
+	return WithOrder(f, phase, order)
+}
+
+// routeFilters 返回路由完整的过滤器列表，按照执行阶段和顺序排序，容量和长度
+// 相同，保证 InvokeHandler 追加元素时不会修改共享的数组
+func routeFilters(filters []Filter, mapper *Mapper) []Filter {
+	n := len(filters)
+	return SortFilters(append(filters[:n:n], mapper.Filters()...))
+}
+
+// Stop 停止 Web 容器，阻塞
+func (c *HttpContainer) Stop(ctx context.Context) {
+	if c.server != nil {
+		err := c.server.Shutdown(ctx)
+		SpringLogger.Infof("shutdown http server on %s return %v", c.Address(), err)
+	}
+}
+
+// ServeHTTP 实现 http.Handler 接口
+func (c *HttpContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	ctx := c.pool.Get().(*httpContext)
+	ctx.reset(w, r)
+	ctx.Set(WebMappingKey, c.WebMapping)
+	defer c.pool.Put(ctx)
+
+	// 整个请求使用同一个路由表
+	ctx.table = c.table.Load().(*routeTable)
+	InvokeHandler(ctx, routingHandler{}, ctx.table.preFilters)
+
+	// 路由匹配之前执行的过滤器返回的错误
+	ctx.table.handleError(ctx)
+
+	ctx.writer.WriteHeaderNow()
+
+	// 在所有过滤器之后才写出 HEAD 请求的响应头，以便处理函数 panic 时
+	// RecoveryFilter 设置的状态码和 GET 请求相同
+	if ctx.head != nil {
+		ctx.head.finish()
+	}
+}
+
+// routingHandler 在路由匹配之前执行的过滤器之后进行路由匹配，然后执行匹配到
+// 的路由或者 404、405 等处理函数。
+type routingHandler struct{}
+
+func (h routingHandler) Invoke(webCtx WebContext) {
+
+	ctx := webCtx.NativeContext().(*httpContext)
+	t, r := ctx.table, ctx.request
+
+	path := CleanPath(r.URL.Path)
+	mapper, head := t.find(ctx, r.Method, path)
+
+	// 末尾的 / 不一致时尝试另一种形式
+	if mapper == nil && t.slashPolicy != StrictSlash {
+		if alt := toggleSlash(path); alt != path {
+			if mapper, head = t.find(ctx, r.Method, alt); mapper != nil {
+				path = alt
+			}
+		}
+	}
+
+	if mapper != nil && t.slashPolicy == RedirectSlash && path != r.URL.Path {
+		InvokeHandler(webCtx, newRedirectHandler(r, path), t.filters)
+	} else if mapper != nil {
+		// 使用 GET 方法的处理函数响应 HEAD 请求，由 ServeHTTP 写出响应头
+		if head {
+			ctx.head = newHeadResponseWriter(ctx.writer.ResponseWriter)
+			ctx.writer.reset(ctx.head)
+		}
+		ctx.path = mapper.Path()
+		ctx.handler = mapper.Handler()
+		ctx.Set(MapperKey, mapper)
+		InvokeHandler(webCtx, ctx.handler, t.routeFilters(mapper))
+		t.handleError(webCtx)
+	} else if allowed := t.allowed(r.Host, path); allowed != 0 {
+		InvokeHandler(webCtx, newAllowHandler(r.Method, allowed), t.filters)
+	} else {
+		InvokeHandler(webCtx, notFoundHandler, t.filters)
+	}
+}
+
+func (h routingHandler) FileLine() (file string, line int, fnName string) {
+	return SpringUtils.FileLine(h.Invoke)
+}
+
+// routeTable 某一时刻的路由表，构建完成之后不再修改
+type routeTable struct {
+	tree          *RouteTree           // 不限制 Host 的路由
+	hosts         []*hostTree          // 限制 Host 的路由，按照匹配的优先级排序
+	routes        map[*Mapper][]Filter // 每个路由完整的过滤器列表
+	guarded       map[*Mapper][]Filter // 没有 AuthorizationFilter 的路由声明了角色或者权限时使用的过滤器列表
+	preFilters    []Filter             // 路由匹配之前执行的容器级别过滤器
+	filters       []Filter             // 路由匹配之后执行的容器级别过滤器
+	errorHandler  ErrorHandler         // 错误处理函数
+	maxParams     int
+	maxHostParams int
+	implicitHead  bool
+	slashPolicy   SlashPolicyEnum
+	proxies       []*net.IPNet // 可信的反向代理
+}
+
+// routeFilters 返回路由的过滤器列表。路由声明了角色或者权限却没有 AuthorizationFilter
+// 时使用默认的 AuthorizationFilter，避免声明的权限要求被跳过。在请求时判断是否
+// 声明了角色或者权限，以便覆盖注册路由之后才调用 Mapper.Secured 等方法的情况。
+func (t *routeTable) routeFilters(mapper *Mapper) []Filter {
+	if mapper.isSecured() {
+		if filters, ok := t.guarded[mapper]; ok {
+			return filters
+		}
+	}
+	return t.routes[mapper]
+}
+
+// handleError 使用错误处理函数处理过滤器和处理函数返回的错误。路由的过滤器
+// 链条执行结束之后立即处理，保证日志过滤器等能够看到最终的响应状态码。
+func (t *routeTable) handleError(ctx WebContext) {
+	if err := GetError(ctx); err != nil {
+		SetError(ctx, nil)
+		if t.errorHandler != nil {
+			t.errorHandler(ctx, err)
+		} else {
+			DefaultErrorHandler(ctx, err)
+		}
+	}
+}
+
+// hostTree 限制 Host 的路由树
+type hostTree struct {
+	pattern *hostPattern
+	tree    *RouteTree
+}
+
+// hostTree 返回 Host 路由对应的路由树，不存在时创建
+func (t *routeTable) hostTree(host string) *RouteTree {
+	if host == "" {
+		return t.tree
+	}
+	for _, h := range t.hosts {
+		if h.pattern.pattern == host {
+			return h.tree
+		}
+	}
+	h := &hostTree{pattern: getHostPattern(host), tree: NewRouteTree()}
+	t.hosts = append(t.hosts, h)
+	return h.tree
+}
+
+// find 查找和请求匹配的路由，Host 路由优先于不限制 Host 的路由。开启
+// ImplicitHead 时 HEAD 请求可以匹配 GET 路由，此时 head 返回 true。
+func (t *routeTable) find(ctx *httpContext, method string, path string) (mapper *Mapper, head bool) {
+	for _, h := range t.hosts {
+		values, ok := h.pattern.match(ctx.request.Host, ctx.host.Values[:0])
+		if !ok {
+			continue
+		}
+		if mapper, head = t.findInTree(h.tree, method, path, &ctx.params); mapper != nil {
+			ctx.host.Names = h.pattern.names
+			ctx.host.Values = values
+			ctx.Set(HostParamsKey, &ctx.host)
+			return
+		}
+	}
+	return t.findInTree(t.tree, method, path, &ctx.params)
+}
+
+// findInTree 在路由树中查找和 method、path 匹配的路由
+func (t *routeTable) findInTree(tree *RouteTree, method string, path string, params *RouteParams) (mapper *Mapper, head bool) {
+	mapper = tree.Find(method, path, params)
+	if mapper == nil && method == http.MethodHead && t.implicitHead {
+		mapper = tree.Find(http.MethodGet, path, params)
+		head = mapper != nil
+	}
+	return
+}
+
+// allowed 返回和 host、path 匹配的所有路由的 HTTP 方法掩码
+func (t *routeTable) allowed(host string, path string) uint32 {
+
+	allowed := t.allowedInTrees(host, path)
+	if t.slashPolicy != StrictSlash {
+		if alt := toggleSlash(path); alt != path {
+			allowed |= t.allowedInTrees(host, alt)
+		}
+	}
+
+	if t.implicitHead && allowed&MethodGet != 0 {
+		allowed |= MethodHead
+	}
+	return allowed
+}
+
+// AllowedMethods 返回当前请求的路径注册的所有 HTTP 方法的掩码，开启 ImplicitHead
+// 时包括 HEAD，路由表不可用 (例如其他容器实现) 时返回 0
+func AllowedMethods(ctx WebContext) uint32 {
+	c, ok := ctx.NativeContext().(*httpContext)
+	if !ok || c.table == nil {
+		return 0
+	}
+	r := c.request
+	return c.table.allowed(r.Host, CleanPath(r.URL.Path))
+}
+
+// allowedInTrees 返回所有和 host 匹配的路由树中和 path 匹配的路由的 HTTP 方法掩码
+func (t *routeTable) allowedInTrees(host string, path string) uint32 {
+	allowed := t.tree.Allowed(path)
+	for _, h := range t.hosts {
+		if _, ok := h.pattern.match(host, nil); ok {
+			allowed |= h.tree.Allowed(path)
+		}
+	}
+	return allowed
+}
+
+// notFoundHandler 没有匹配到路由时的处理函数
+var notFoundHandler = FUNC(func(ctx WebContext) {
+	ctx.String(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+})
+
+// allowHandler 路径匹配但是方法不匹配时的处理函数，OPTIONS 请求返回 204，
+// 其他请求返回 405，并且都在 Allow 响应头中列出该路径支持的方法。
+type allowHandler struct {
+	allowed uint32
+	options bool
+}
+
+func newAllowHandler(method string, allowed uint32) *allowHandler {
+	return &allowHandler{
+		allowed: allowed | MethodOptions,
+		options: method == http.MethodOptions,
+	}
+}
+
+func (h *allowHandler) Invoke(ctx WebContext) {
+	ctx.Header(HeaderAllow, allowHeader(h.allowed))
+	if h.options {
+		ctx.NoContent(http.StatusNoContent)
+	} else {
+		code := http.StatusMethodNotAllowed
+		ctx.String(code, http.StatusText(code))
+	}
+}
+
+func (h *allowHandler) FileLine() (file string, line int, fnName string) {
+	return SpringUtils.FileLine(h.Invoke)
+}
+
+// redirectHandler 请求路径不是规范的路由地址时重定向到规范地址的处理函数
+type redirectHandler struct {
+	code     int
+	location string
+}
+
+func newRedirectHandler(r *http.Request, path string) *redirectHandler {
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	u := url.URL{Path: path, RawQuery: r.URL.RawQuery}
+	return &redirectHandler{code: code, location: u.String()}
+}
+
+func (h *redirectHandler) Invoke(ctx WebContext) {
+	ctx.Redirect(h.code, h.location)
+}
+
+func (h *redirectHandler) FileLine() (file string, line int, fnName string) {
+	return SpringUtils.FileLine(h.Invoke)
+}
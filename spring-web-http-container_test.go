@@ -0,0 +1,599 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-error"
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+type echoRequest struct {
+	Id   int    `param:"id"`
+	Name string `query:"name" json:"name"`
+}
+
+type echoResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// doRequest 发送请求并返回状态码和响应体
+func doRequest(t *testing.T, method string, url string, body string, header ...string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		if header[i] == "Host" {
+			req.Host = header[i+1]
+		} else {
+			req.Header.Set(header[i], header[i+1])
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+// rewriteFilter 在路由匹配之前修改请求路径
+type rewriteFilter struct {
+	from, to string
+}
+
+func (f *rewriteFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.FilterChain) {
+	if r := ctx.Request(); r.URL.Path == f.from {
+		r.URL.Path = f.to
+	}
+	chain.Next(ctx)
+}
+
+func (f *rewriteFilter) Phase() SpringWeb.FilterPhase {
+	return SpringWeb.PreRoutingPhase
+}
+
+func (f *rewriteFilter) Order() int {
+	return 0
+}
+
+type stringFilter struct {
+	s string
+}
+
+func (f *stringFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.FilterChain) {
+	ctx.Header("X-Filter", ctx.ResponseWriter().Header().Get("X-Filter")+f.s)
+	chain.Next(ctx)
+}
+
+func TestHttpContainer(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1", ImplicitHead: true})
+	c.SetEnableSwagger(false)
+	c.AddFilter(SpringWeb.WithOrder(&stringFilter{"z"}, SpringWeb.PostHandlerPhase, 0))
+	c.AddFilter(&stringFilter{"c"})
+	c.AddFilter(&rewriteFilter{from: "/old/hello", to: "/hello"})
+	c.AddFilter(SpringWeb.NewConditionalFilter(&stringFilter{"u"}).
+		Include("/user/*").Exclude("/user/me").Method(SpringWeb.MethodGet))
+
+	c.GetMapping("/hello", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "hello %s", ctx.QueryParam("name"))
+	}, &stringFilter{"m"})
+
+	c.GetMapping("/user/{id}", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "user %s", ctx.PathParam("id"))
+	}).Name("user")
+
+	c.GetMapping("/redirect/{id}", func(ctx SpringWeb.WebContext) {
+		u, err := SpringWeb.URLFor(ctx, "user", "id", ctx.PathParam("id"))
+		if err != nil {
+			panic(err)
+		}
+		ctx.Redirect(http.StatusFound, u)
+	})
+
+	c.GetMapping("/user/me", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "me")
+	})
+
+	c.GetMapping("/static/{*:file}", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "%s %s", ctx.PathParam("file"), ctx.PathParam("*"))
+	})
+
+	c.PostBinding("/echo/{id}", func(ctx context.Context, req *echoRequest) *echoResponse {
+		return &echoResponse{Id: req.Id, Name: req.Name}
+	})
+
+	c.Request(SpringWeb.MethodGet|SpringWeb.MethodOptions, "/custom", SpringWeb.FUNC(func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "custom")
+	}))
+
+	c.GetMapping("/panic", func(ctx SpringWeb.WebContext) {
+		panic("oops")
+	})
+
+	c.Host("api.example.com").GetMapping("/hello", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "api")
+	})
+
+	c.Host("{tenant}.example.com", &stringFilter{"h"}).GetMapping("/hello", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "tenant %s", SpringWeb.HostParam(ctx, "tenant"))
+	})
+
+	c.Host("*.example.org").PostMapping("/hello", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "org %s", SpringWeb.HostParam(ctx, "*"))
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("static", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/hello?name=go", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, body, "hello go")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cmz")
+		_, body = doRequest(t, http.MethodGet, url+"/old/hello?name=go", "")
+		assert.Equal(t, body, "hello go")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cmz")
+	})
+
+	t.Run("param", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/user/123", "")
+		assert.Equal(t, body, "user 123")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cuz")
+		resp, body = doRequest(t, http.MethodGet, url+"/user/me", "")
+		assert.Equal(t, body, "me")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cz")
+		resp, _ = doRequest(t, http.MethodHead, url+"/user/123", "")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cz")
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/redirect/7", "")
+		assert.Equal(t, resp.Request.URL.Path, "/user/7")
+		assert.Equal(t, body, "user 7")
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodGet, url+"/static/js/a.js", "")
+		assert.Equal(t, body, "js/a.js js/a.js")
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodPost, url+"/echo/9", `{"name":"go"}`,
+			SpringWeb.HeaderContentType, SpringWeb.MIMEApplicationJSON)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, body, `{"code":200,"msg":"SUCCESS","data":{"id":9,"name":"go"}}`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodGet, url+"/none", "")
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+		resp, _ = doRequest(t, http.MethodOptions, url+"/none", "")
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodPost, url+"/hello", "")
+		assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "GET, HEAD, OPTIONS")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cz")
+		resp, _ = doRequest(t, http.MethodDelete, url+"/echo/1", "")
+		assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "POST, OPTIONS")
+	})
+
+	t.Run("options", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodOptions, url+"/user/me", "")
+		assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "GET, HEAD, OPTIONS")
+		resp, body := doRequest(t, http.MethodOptions, url+"/custom", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, body, "custom")
+	})
+
+	t.Run("implicit head", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodHead, url+"/hello?name=go", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, resp.ContentLength, int64(len("hello go")))
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderContentType), SpringWeb.MIMETextPlainCharsetUTF8)
+		assert.Equal(t, resp.Header.Get("X-Filter"), "cmz")
+		assert.Equal(t, body, "")
+		resp, _ = doRequest(t, http.MethodHead, url+"/echo/1", "")
+		assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "POST, OPTIONS")
+	})
+
+	t.Run("host", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodGet, url+"/hello", "", "Host", "API.example.com:18080")
+		assert.Equal(t, body, "api")
+		resp, body := doRequest(t, http.MethodGet, url+"/hello", "", "Host", "acme.example.com")
+		assert.Equal(t, body, "tenant acme")
+		assert.Equal(t, resp.Header.Get("X-Filter"), "chz")
+		_, body = doRequest(t, http.MethodGet, url+"/hello?name=go", "", "Host", "a.b.example.com")
+		assert.Equal(t, body, "hello go")
+		_, body = doRequest(t, http.MethodPost, url+"/hello", "", "Host", "a.b.example.org")
+		assert.Equal(t, body, "org a.b")
+		resp, _ = doRequest(t, http.MethodPost, url+"/hello", "", "Host", "example.org")
+		assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "GET, HEAD, OPTIONS")
+		resp, _ = doRequest(t, http.MethodOptions, url+"/hello", "", "Host", "www.example.org")
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "GET, HEAD, POST, OPTIONS")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodGet, url+"/panic", "")
+		assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+	})
+}
+
+func TestHttpContainer_SlashPolicy(t *testing.T) {
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	do := func(t *testing.T, method string, url string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, url, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	start := func(policy SpringWeb.SlashPolicyEnum) *SpringWeb.HttpContainer {
+		c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1", SlashPolicy: policy})
+		c.SetEnableSwagger(false)
+		c.GetMapping("/users", func(ctx SpringWeb.WebContext) {
+			ctx.String(http.StatusOK, "users")
+		})
+		c.PostMapping("/books/", func(ctx SpringWeb.WebContext) {
+			ctx.String(http.StatusOK, "books")
+		})
+		c.Start()
+		return c
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		c := start(SpringWeb.StrictSlash)
+		defer c.Stop(context.Background())
+		url := "http://" + c.Address()
+		resp, _ := do(t, http.MethodGet, url+"/users/")
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+		_, body := do(t, http.MethodGet, url+"//a/../users")
+		assert.Equal(t, body, "users")
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		c := start(SpringWeb.RedirectSlash)
+		defer c.Stop(context.Background())
+		url := "http://" + c.Address()
+		resp, _ := do(t, http.MethodGet, url+"/users/?page=1")
+		assert.Equal(t, resp.StatusCode, http.StatusMovedPermanently)
+		assert.Equal(t, resp.Header.Get("Location"), "/users?page=1")
+		resp, _ = do(t, http.MethodGet, url+"/a/..//users")
+		assert.Equal(t, resp.StatusCode, http.StatusMovedPermanently)
+		assert.Equal(t, resp.Header.Get("Location"), "/users")
+		resp, _ = do(t, http.MethodPost, url+"/books")
+		assert.Equal(t, resp.StatusCode, http.StatusPermanentRedirect)
+		assert.Equal(t, resp.Header.Get("Location"), "/books/")
+		_, body := do(t, http.MethodGet, url+"/users")
+		assert.Equal(t, body, "users")
+	})
+
+	t.Run("match both", func(t *testing.T) {
+		c := start(SpringWeb.MatchBothSlash)
+		defer c.Stop(context.Background())
+		url := "http://" + c.Address()
+		_, body := do(t, http.MethodGet, url+"/users/")
+		assert.Equal(t, body, "users")
+		_, body = do(t, http.MethodPost, url+"/books")
+		assert.Equal(t, body, "books")
+		resp, _ := do(t, http.MethodGet, url+"/books")
+		assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderAllow), "POST, OPTIONS")
+	})
+}
+
+func TestHttpContainer_Runtime(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.Swagger().WithTitle("runtime")
+	c.GetMapping("/ping", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "pong")
+	}).Swagger("ping")
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	// 注册路由的同时持续发送请求，-race 下检查并发安全
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			doRequest(t, http.MethodGet, url+"/ping", "")
+		}
+	}()
+
+	hello := SpringWeb.NewMapper(SpringWeb.MethodGet, "/hello", SpringWeb.FUNC(func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "hello")
+	}), nil).WithSwagger(SpringWeb.NewOperation("hello"))
+	c.AddMapper(hello)
+	<-done
+
+	_, body := doRequest(t, http.MethodGet, url+"/hello", "")
+	assert.Equal(t, body, "hello")
+	_, doc := doRequest(t, http.MethodGet, url+"/swagger/doc.json", "")
+	assert.Equal(t, strings.Contains(doc, `"/hello"`), true)
+	assert.Equal(t, strings.Contains(doc, `"/ping"`), true)
+
+	// 替换
+	c.GetMapping("/hello", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "hello again")
+	})
+	_, body = doRequest(t, http.MethodGet, url+"/hello", "")
+	assert.Equal(t, body, "hello again")
+
+	// 删除
+	assert.Equal(t, c.RemoveMapper(c.Mappers()[hello.Key()]), true)
+	resp, _ := doRequest(t, http.MethodGet, url+"/hello", "")
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	_, doc = doRequest(t, http.MethodGet, url+"/swagger/doc.json", "")
+	assert.Equal(t, strings.Contains(doc, `"/hello"`), false)
+	assert.Equal(t, strings.Contains(doc, `"/ping"`), true)
+}
+
+func TestHttpContainer_RuntimeConflict(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.SetConflictPolicy(SpringWeb.LastWinsOnConflict)
+	c.Request(SpringWeb.MethodGetPost, "/user/{id}", SpringWeb.FUNC(func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "all")
+	}))
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	// 请求的同时注册冲突的路由，-race 下检查先注册的 Mapper 没有被并发修改
+	started := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			doRequest(t, http.MethodPost, url+"/user/1", "")
+			if i == 0 {
+				close(started)
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	c.GetMapping("/user/{name}", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "get")
+	})
+	close(stop)
+	<-done
+
+	_, body := doRequest(t, http.MethodGet, url+"/user/1", "")
+	assert.Equal(t, body, "get")
+	_, body = doRequest(t, http.MethodPost, url+"/user/1", "")
+	assert.Equal(t, body, "all")
+}
+
+// errorFilter 把处理函数返回的 HttpError 的状态码写入响应头，并吞掉 418 错误
+type errorFilter struct{}
+
+func (f *errorFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.EFilterChain) error {
+	err := chain.Next(ctx)
+	if e, ok := err.(*SpringWeb.HttpError); ok {
+		ctx.Header("X-Error", strconv.Itoa(e.Code))
+		if e.Code == http.StatusTeapot {
+			ctx.String(http.StatusOK, "recovered")
+			return nil
+		}
+	}
+	return err
+}
+
+// wrappedError 通过 Unwrap 方法包装其他错误
+type wrappedError struct {
+	err error
+}
+
+func (e *wrappedError) Error() string { return "wrapped: " + e.err.Error() }
+func (e *wrappedError) Unwrap() error { return e.err }
+
+// causeError 通过 Cause 方法包装其他错误
+type causeError struct {
+	err error
+}
+
+func (e *causeError) Error() string { return "cause: " + e.err.Error() }
+func (e *causeError) Cause() error  { return e.err }
+
+func TestHttpContainer_Error(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.EFILTER(&errorFilter{}))
+
+	c.HandleGet("/ok", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		ctx.String(http.StatusOK, "ok")
+		return nil
+	}))
+	c.HandleGet("/http", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		return SpringWeb.NewHttpError(http.StatusForbidden, "no access")
+	}))
+	c.HandleGet("/teapot", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		return SpringWeb.NewHttpError(http.StatusTeapot)
+	}))
+	c.HandleGet("/rpc", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		return SpringWeb.NewRpcResultError(SpringError.ERROR.Error(errors.New("oops")))
+	}))
+	c.HandleGet("/validate", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		var req struct {
+			Name string `query:"name" validate:"required"`
+		}
+		return ctx.Bind(&req)
+	}))
+	c.HandleGet("/wrapped", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		return &causeError{&wrappedError{SpringWeb.NewHttpError(http.StatusConflict, "conflict")}}
+	}))
+	c.HandleGet("/other", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		return errors.New("secret")
+	}))
+	c.HandleGet("/written", SpringWeb.EFUNC(func(ctx SpringWeb.WebContext) error {
+		ctx.String(http.StatusAccepted, "partial")
+		return errors.New("late")
+	}))
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("ok", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/ok", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, body, "ok")
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/http", "")
+		assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+		assert.Equal(t, resp.Header.Get("X-Error"), "403")
+		assert.Equal(t, body, "no access")
+	})
+
+	t.Run("wrapped http error", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/wrapped", "")
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, body, "conflict")
+	})
+
+	t.Run("handled by filter", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/teapot", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, body, "recovered")
+	})
+
+	t.Run("rpc result", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/rpc", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, strings.Contains(body, `"err":"oops"`), true)
+		assert.Equal(t, strings.Contains(body, `"code":-1`), true)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodGet, url+"/validate", "")
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+		resp, _ = doRequest(t, http.MethodGet, url+"/validate?name=x", "")
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("internal", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/other", "")
+		assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+		assert.Equal(t, body, http.StatusText(http.StatusInternalServerError))
+	})
+
+	t.Run("written", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/written", "")
+		assert.Equal(t, resp.StatusCode, http.StatusAccepted)
+		assert.Equal(t, body, "partial")
+	})
+}
+
+func TestHttpContext_ClientIP(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{
+		IP:             "127.0.0.1",
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"},
+	})
+	c.GetMapping("/ip", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "%s", ctx.ClientIP())
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	for _, testCase := range []struct {
+		remote string
+		xff    string
+		realIP string
+		ip     string
+	}{
+		{"1.2.3.4:1000", "5.6.7.8", "5.6.7.8", "1.2.3.4"},
+		{"10.0.0.1:1000", "5.6.7.8, 10.0.0.2", "", "5.6.7.8"},
+		{"10.0.0.1:1000", "9.9.9.9, 5.6.7.8, 192.168.1.1", "", "5.6.7.8"},
+		{"10.0.0.1:1000", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"192.168.1.1:1000", "", "5.6.7.8", "5.6.7.8"},
+		{"192.168.1.2:1000", "", "5.6.7.8", "192.168.1.2"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = testCase.remote
+		if testCase.xff != "" {
+			req.Header.Set(SpringWeb.HeaderXForwardedFor, testCase.xff)
+		}
+		if testCase.realIP != "" {
+			req.Header.Set(SpringWeb.HeaderXRealIP, testCase.realIP)
+		}
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, req)
+		assert.Equal(t, w.Body.String(), testCase.ip, testCase.remote+" "+testCase.xff)
+	}
+
+	func() {
+		defer func() {
+			assert.Equal(t, recover() != nil, true)
+		}()
+		SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{TrustedProxies: []string{"10.0.0"}})
+	}()
+}
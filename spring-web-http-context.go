@@ -0,0 +1,544 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-spring/go-spring-logger"
+)
+
+// defaultMemory 解析 multipart 表单时使用的默认内存大小
+const defaultMemory = 32 << 20 // 32 MB
+
+// httpContext 基于 net/http 实现的 WebContext
+type httpContext struct {
+	SpringLogger.LoggerContext
+
+	writer  responseWriter
+	w       http.ResponseWriter // 过滤器替换的 ResponseWriter，为 nil 时使用 writer
+	request *http.Request
+
+	path    string // 注册的路由地址
+	handler Handler
+	params  RouteParams
+	host    RouteParams         // Host 路由参数
+	table   *routeTable         // 当前请求使用的路由表
+	head    *headResponseWriter // 使用 GET 方法的处理函数响应 HEAD 请求时的 ResponseWriter
+
+	query url.Values
+	store map[string]interface{}
+}
+
+// newHttpContext httpContext 的构造函数
+func newHttpContext() *httpContext {
+	return &httpContext{}
+}
+
+// reset 重置 httpContext 以便复用
+func (c *httpContext) reset(w http.ResponseWriter, r *http.Request) {
+	c.writer.reset(w)
+	c.w = nil
+	c.SetRequest(r)
+	c.path = ""
+	c.handler = nil
+	c.params.Reset()
+	c.host.Reset()
+	c.table = nil
+	c.head = nil
+	c.query = nil
+	for k := range c.store {
+		delete(c.store, k)
+	}
+}
+
+// NativeContext 内置容器没有封装的底层上下文对象，返回其自身
+func (c *httpContext) NativeContext() interface{} {
+	return c
+}
+
+// Get retrieves data from the context.
+func (c *httpContext) Get(key string) interface{} {
+	return c.store[key]
+}
+
+// Set saves data in the context.
+func (c *httpContext) Set(key string, val interface{}) {
+	if c.store == nil {
+		c.store = make(map[string]interface{})
+	}
+	c.store[key] = val
+}
+
+// Request returns `*http.Request`.
+func (c *httpContext) Request() *http.Request {
+	return c.request
+}
+
+// SetRequest sets `*http.Request`.
+func (c *httpContext) SetRequest(r *http.Request) {
+	c.request = r
+	c.LoggerContext = newLoggerContext(r.Context())
+}
+
+// IsTLS returns true if HTTP connection is TLS otherwise false.
+func (c *httpContext) IsTLS() bool {
+	return c.request.TLS != nil
+}
+
+// IsWebSocket returns true if HTTP connection is WebSocket otherwise false.
+func (c *httpContext) IsWebSocket() bool {
+	upgrade := c.request.Header.Get("Upgrade")
+	return strings.ToLower(upgrade) == "websocket"
+}
+
+// Scheme returns the HTTP protocol scheme, `http` or `https`.
+func (c *httpContext) Scheme() string {
+	if c.IsTLS() {
+		return "https"
+	}
+	if scheme := c.request.Header.Get(HeaderXForwardedProto); scheme != "" {
+		return scheme
+	}
+	if scheme := c.request.Header.Get(HeaderXForwardedProtocol); scheme != "" {
+		return scheme
+	}
+	if ssl := c.request.Header.Get(HeaderXForwardedSsl); ssl == "on" {
+		return "https"
+	}
+	if scheme := c.request.Header.Get(HeaderXUrlScheme); scheme != "" {
+		return scheme
+	}
+	return "http"
+}
+
+// ClientIP 返回客户端的 IP。请求来自 ContainerConfig.TrustedProxies 中的可信代理
+// 时，从右向左跳过 X-Forwarded-For 中的可信代理，返回第一个不可信的地址，没有
+// X-Forwarded-For 时使用 X-Real-Ip；否则返回连接的远端地址，以免客户端伪造 IP。
+func (c *httpContext) ClientIP() string {
+
+	remote := c.request.RemoteAddr
+	if ip, _, err := net.SplitHostPort(strings.TrimSpace(remote)); err == nil {
+		remote = ip
+	}
+
+	if !c.trusted(remote) {
+		return remote
+	}
+
+	if xff := c.request.Header.Get(HeaderXForwardedFor); xff != "" {
+		ips := strings.Split(xff, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if i == 0 || !c.trusted(ip) {
+				return ip
+			}
+		}
+	}
+
+	if ip := c.request.Header.Get(HeaderXRealIP); ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	return remote
+}
+
+// trusted 返回 ip 是否为可信的代理
+func (c *httpContext) trusted(ip string) bool {
+	if c.table == nil || len(c.table.proxies) == 0 {
+		return false
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range c.table.proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Path returns the registered path for the handler.
+func (c *httpContext) Path() string {
+	return c.path
+}
+
+// Handler returns the matched handler by router.
+func (c *httpContext) Handler() Handler {
+	return c.handler
+}
+
+// ContentType returns the Content-Type header of the request.
+func (c *httpContext) ContentType() string {
+	return c.request.Header.Get(HeaderContentType)
+}
+
+// GetHeader returns value from request headers.
+func (c *httpContext) GetHeader(key string) string {
+	return c.request.Header.Get(key)
+}
+
+// GetRawData return stream data.
+func (c *httpContext) GetRawData() ([]byte, error) {
+	return ioutil.ReadAll(c.request.Body)
+}
+
+// PathParam returns path parameter by name.
+func (c *httpContext) PathParam(name string) string {
+	return c.params.Get(name)
+}
+
+// PathParamNames returns path parameter names.
+func (c *httpContext) PathParamNames() []string {
+	return c.params.Names
+}
+
+// PathParamValues returns path parameter values.
+func (c *httpContext) PathParamValues() []string {
+	return c.params.Values
+}
+
+// QueryParam returns the query param for the provided name.
+func (c *httpContext) QueryParam(name string) string {
+	return c.QueryParams().Get(name)
+}
+
+// QueryParams returns the query parameters as `url.Values`.
+func (c *httpContext) QueryParams() url.Values {
+	if c.query == nil {
+		c.query = c.request.URL.Query()
+	}
+	return c.query
+}
+
+// QueryString returns the URL query string.
+func (c *httpContext) QueryString() string {
+	return c.request.URL.RawQuery
+}
+
+// FormValue returns the form field value for the provided name.
+func (c *httpContext) FormValue(name string) string {
+	return c.request.FormValue(name)
+}
+
+// FormParams returns the form parameters as `url.Values`.
+func (c *httpContext) FormParams() (url.Values, error) {
+	if strings.HasPrefix(c.ContentType(), MIMEMultipartForm) {
+		if err := c.request.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := c.request.ParseForm(); err != nil {
+			return nil, err
+		}
+	}
+	return c.request.Form, nil
+}
+
+// FormFile returns the multipart form file for the provided name.
+func (c *httpContext) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.request.MultipartForm == nil {
+		if err := c.request.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return nil, err
+		}
+	}
+	f, fh, err := c.request.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+	return fh, nil
+}
+
+// SaveUploadedFile uploads the form file to specific dst.
+func (c *httpContext) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// multipartMemory 返回解析 multipart 表单时使用的最大内存
+func (c *httpContext) multipartMemory() int64 {
+	if n, ok := c.Get(MultipartMemoryKey).(int64); ok {
+		return n
+	}
+	return defaultMemory
+}
+
+// MultipartForm returns the multipart form.
+func (c *httpContext) MultipartForm() (*multipart.Form, error) {
+	err := c.request.ParseMultipartForm(c.multipartMemory())
+	return c.request.MultipartForm, err
+}
+
+// Cookie returns the named cookie provided in the request.
+func (c *httpContext) Cookie(name string) (*http.Cookie, error) {
+	return c.request.Cookie(name)
+}
+
+// Cookies returns the HTTP cookies sent with the request.
+func (c *httpContext) Cookies() []*http.Cookie {
+	return c.request.Cookies()
+}
+
+// Bind binds the request body into provided type `i`.
+func (c *httpContext) Bind(i interface{}) error {
+	if err := bindRequest(c, i); err != nil {
+		return err
+	}
+	return Validator.Validate(i)
+}
+
+// ResponseWriter returns `http.ResponseWriter`.
+func (c *httpContext) ResponseWriter() http.ResponseWriter {
+	if c.w != nil {
+		return c.w
+	}
+	return &c.writer
+}
+
+// SetResponseWriter sets `http.ResponseWriter`.
+func (c *httpContext) SetResponseWriter(w http.ResponseWriter) {
+	c.w = w
+}
+
+// Status sets the HTTP response code.
+func (c *httpContext) Status(code int) {
+	c.ResponseWriter().WriteHeader(code)
+}
+
+// Header is a intelligent shortcut for c.Writer.Header().Set(key, value).
+func (c *httpContext) Header(key, value string) {
+	if value == "" {
+		c.ResponseWriter().Header().Del(key)
+	} else {
+		c.ResponseWriter().Header().Set(key, value)
+	}
+}
+
+// SetCookie adds a `Set-Cookie` header in HTTP response.
+func (c *httpContext) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(c.ResponseWriter(), cookie)
+}
+
+// NoContent sends a response with no body and a status code.
+func (c *httpContext) NoContent(code int) {
+	w := c.ResponseWriter()
+	w.WriteHeader(code)
+	if rw, ok := w.(ResponseWriter); ok {
+		rw.WriteHeaderNow()
+	}
+}
+
+// String writes the given string into the response body.
+func (c *httpContext) String(code int, format string, values ...interface{}) {
+	if len(values) > 0 {
+		format = fmt.Sprintf(format, values...)
+	}
+	c.Blob(code, MIMETextPlainCharsetUTF8, []byte(format))
+}
+
+// HTML sends an HTTP response with status code.
+func (c *httpContext) HTML(code int, html string) {
+	c.HTMLBlob(code, []byte(html))
+}
+
+// HTMLBlob sends an HTTP blob response with status code.
+func (c *httpContext) HTMLBlob(code int, b []byte) {
+	c.Blob(code, MIMETextHTMLCharsetUTF8, b)
+}
+
+// JSON sends a JSON response with status code.
+func (c *httpContext) JSON(code int, i interface{}) {
+	c.JSONPretty(code, i, "")
+}
+
+// JSONPretty sends a pretty-print JSON with status code.
+func (c *httpContext) JSONPretty(code int, i interface{}, indent string) {
+	var (
+		b   []byte
+		err error
+	)
+	if indent != "" {
+		b, err = json.MarshalIndent(i, "", indent)
+	} else {
+		b, err = json.Marshal(i)
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.JSONBlob(code, b)
+}
+
+// JSONBlob sends a JSON blob response with status code.
+func (c *httpContext) JSONBlob(code int, b []byte) {
+	c.Blob(code, MIMEApplicationJSONCharsetUTF8, b)
+}
+
+// JSONP sends a JSONP response with status code.
+func (c *httpContext) JSONP(code int, callback string, i interface{}) {
+	b, err := json.Marshal(i)
+	if err != nil {
+		panic(err)
+	}
+	c.JSONPBlob(code, callback, b)
+}
+
+// JSONPBlob sends a JSONP blob response with status code.
+func (c *httpContext) JSONPBlob(code int, callback string, b []byte) {
+	var buf bytes.Buffer
+	buf.WriteString(callback)
+	buf.WriteString("(")
+	buf.Write(b)
+	buf.WriteString(");")
+	c.Blob(code, MIMEApplicationJavaScriptCharsetUTF8, buf.Bytes())
+}
+
+// XML sends an XML response with status code.
+func (c *httpContext) XML(code int, i interface{}) {
+	c.XMLPretty(code, i, "")
+}
+
+// XMLPretty sends a pretty-print XML with status code.
+func (c *httpContext) XMLPretty(code int, i interface{}, indent string) {
+	var (
+		b   []byte
+		err error
+	)
+	if indent != "" {
+		b, err = xml.MarshalIndent(i, "", indent)
+	} else {
+		b, err = xml.Marshal(i)
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.XMLBlob(code, b)
+}
+
+// XMLBlob sends an XML blob response with status code.
+func (c *httpContext) XMLBlob(code int, b []byte) {
+	c.Blob(code, MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), b...))
+}
+
+// Blob sends a blob response with status code and content type.
+func (c *httpContext) Blob(code int, contentType string, b []byte) {
+	c.Header(HeaderContentType, contentType)
+	w := c.ResponseWriter()
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
+
+// Stream sends a streaming response with status code and content type.
+func (c *httpContext) Stream(code int, contentType string, r io.Reader) {
+	c.Header(HeaderContentType, contentType)
+	w := c.ResponseWriter()
+	w.WriteHeader(code)
+	_, _ = io.Copy(w, r)
+}
+
+// File sends a response with the content of the file.
+func (c *httpContext) File(file string) {
+	http.ServeFile(c.ResponseWriter(), c.request, file)
+}
+
+// Attachment sends a response as attachment, prompting client to save the file.
+func (c *httpContext) Attachment(file string, name string) {
+	c.contentDisposition(file, name, "attachment")
+}
+
+// Inline sends a response as inline, opening the file in the browser.
+func (c *httpContext) Inline(file string, name string) {
+	c.contentDisposition(file, name, "inline")
+}
+
+func (c *httpContext) contentDisposition(file, name, dispositionType string) {
+	if name == "" {
+		name = filepath.Base(file)
+	}
+	c.Header(HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", dispositionType, name))
+	c.File(file)
+}
+
+// Redirect redirects the request to a provided URL with status code.
+func (c *httpContext) Redirect(code int, url string) {
+	http.Redirect(c.ResponseWriter(), c.request, url, code)
+}
+
+// SSEvent writes a Server-Sent Event into the body stream.
+func (c *httpContext) SSEvent(name string, message interface{}) {
+
+	w := c.ResponseWriter()
+	h := w.Header()
+	if h.Get(HeaderContentType) == "" {
+		h.Set(HeaderContentType, "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+	}
+
+	var data string
+	switch v := message.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(message)
+		if err != nil {
+			panic(err)
+		}
+		data = string(b)
+	}
+
+	var buf bytes.Buffer
+	if name != "" {
+		buf.WriteString("event: " + name + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	_, _ = w.Write(buf.Bytes())
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
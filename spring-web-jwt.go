@@ -0,0 +1,599 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWTClaimsKey WebContext 中保存 JWT Claims 的 Key
+const JWTClaimsKey = "@JWTClaims"
+
+// 支持的 JWT 签名算法
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+)
+
+// minHMACKeySize HS256 密钥的最小长度，不能短于 SHA-256 的输出长度
+const minHMACKeySize = 32
+
+// JWT 校验失败的原因
+var (
+	ErrJWTMissing      = errors.New("jwt: missing token")
+	ErrJWTMalformed    = errors.New("jwt: malformed token")
+	ErrJWTAlgorithm    = errors.New("jwt: unsupported algorithm")
+	ErrJWTKeyNotFound  = errors.New("jwt: key not found")
+	ErrJWTSignature    = errors.New("jwt: invalid signature")
+	ErrJWTExpired      = errors.New("jwt: token is expired")
+	ErrJWTNotYetValid  = errors.New("jwt: token is not valid yet")
+	ErrJWTInvalidIss   = errors.New("jwt: invalid issuer")
+	ErrJWTInvalidAud   = errors.New("jwt: invalid audience")
+	ErrJWTInvalidClaim = errors.New("jwt: invalid claim")
+)
+
+// JWTClaims JWT 的 Claims，数字类型的值为 json.Number
+type JWTClaims map[string]interface{}
+
+// String 返回字符串类型的 Claim
+func (c JWTClaims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings 返回字符串或者字符串数组类型的 Claim
+func (c JWTClaims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		r := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				r = append(r, s)
+			}
+		}
+		return r
+	}
+	return nil
+}
+
+// Time 返回 NumericDate 类型的 Claim，不存在时 ok 为 false
+func (c JWTClaims) Time(name string) (t time.Time, ok bool, err error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, isNumber := v.(json.Number)
+	if !isNumber {
+		return time.Time{}, true, ErrJWTInvalidClaim
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, true, ErrJWTInvalidClaim
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true, nil
+}
+
+// Subject 返回 sub
+func (c JWTClaims) Subject() string {
+	return c.String("sub")
+}
+
+// Issuer 返回 iss
+func (c JWTClaims) Issuer() string {
+	return c.String("iss")
+}
+
+// Audience 返回 aud
+func (c JWTClaims) Audience() []string {
+	return c.Strings("aud")
+}
+
+// GetJWTClaims 返回 JWTFilter 校验通过的 Claims，没有时返回 nil
+func GetJWTClaims(ctx WebContext) JWTClaims {
+	c, _ := ctx.Get(JWTClaimsKey).(JWTClaims)
+	return c
+}
+
+// jwtClaimsContextKey context.Context 中保存 JWT Claims 的 Key
+type jwtClaimsContextKey struct{}
+
+// JWTClaimsFromContext 返回 context.Context 中保存的 Claims，用于 BIND 处理函数
+func JWTClaimsFromContext(ctx context.Context) JWTClaims {
+	c, _ := ctx.Value(jwtClaimsContextKey{}).(JWTClaims)
+	return c
+}
+
+// ContextWithJWTClaims 返回保存了 Claims 的 context.Context
+func ContextWithJWTClaims(ctx context.Context, claims JWTClaims) context.Context {
+	return context.WithValue(ctx, jwtClaimsContextKey{}, claims)
+}
+
+// JWTKeySource 提供校验签名的密钥，HS256 使用 []byte，RS256 使用 *rsa.PublicKey，
+// ES256 使用 *ecdsa.PublicKey
+type JWTKeySource interface {
+	Key(kid string, alg string) (interface{}, error)
+}
+
+// JWTKeyFunc 函数形式的 JWTKeySource
+type JWTKeyFunc func(kid string, alg string) (interface{}, error)
+
+func (f JWTKeyFunc) Key(kid string, alg string) (interface{}, error) {
+	return f(kid, alg)
+}
+
+// JWTStaticKey 返回不区分 kid 的 JWTKeySource，HS256 密钥短于 32 字节时校验总是失败
+func JWTStaticKey(key interface{}) JWTKeySource {
+	return JWTKeyFunc(func(kid string, alg string) (interface{}, error) {
+		return key, nil
+	})
+}
+
+// JWKSet JSON Web Key Set，支持 oct、RSA 和 P-256 的 EC 密钥
+type JWKSet struct {
+	keys map[string]*jwk
+}
+
+// jwk 解析后的 JSON Web Key
+type jwk struct {
+	alg string
+	key interface{}
+}
+
+// ParseJWKSet 解析 JSON 格式的 JWKS。oct 密钥不能短于 32 字节，包含多个密钥时
+// 每个密钥都必须有唯一的 kid。
+func ParseJWKSet(data []byte) (*JWKSet, error) {
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			K   string `json:"k"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	s := &JWKSet{keys: make(map[string]*jwk)}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key interface{}
+		var err error
+		switch k.Kty {
+		case "oct":
+			key, err = hmacKey(k.K)
+		case "RSA":
+			key, err = rsaPublicKey(k.N, k.E)
+		case "EC":
+			key, err = ecPublicKey(k.Crv, k.X, k.Y)
+		default:
+			err = fmt.Errorf("unsupported kty %q", k.Kty)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %d (kid %q): %v", i, k.Kid, err)
+		}
+		if len(set.Keys) > 1 {
+			if k.Kid == "" {
+				return nil, fmt.Errorf("jwks: key %d: missing kid", i)
+			}
+			if _, ok := s.keys[k.Kid]; ok {
+				return nil, fmt.Errorf("jwks: key %d: duplicate kid %q", i, k.Kid)
+			}
+		}
+		s.keys[k.Kid] = &jwk{alg: k.Alg, key: key}
+	}
+	return s, nil
+}
+
+// hmacKey 返回 JWK 表示的 HMAC 密钥
+func hmacKey(k string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < minHMACKeySize {
+		return nil, fmt.Errorf("HMAC key must be at least %d bytes", minHMACKeySize)
+	}
+	return b, nil
+}
+
+// rsaPublicKey 返回 JWK 表示的 RSA 公钥
+func rsaPublicKey(n, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(eb)
+	if len(nb) == 0 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(exp.Int64())}, nil
+}
+
+// ecPublicKey 返回 JWK 表示的 EC 公钥，只支持 P-256
+func ecPublicKey(crv, x, y string) (*ecdsa.PublicKey, error) {
+	if crv != "P-256" {
+		return nil, fmt.Errorf("unsupported crv %q", crv)
+	}
+	xb, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, err
+	}
+	yb, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb)}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return nil, errors.New("invalid EC key")
+	}
+	return key, nil
+}
+
+// Key 返回 kid 对应的密钥，JWK 指定了 alg 时必须和 JWT 的 alg 相同
+func (s *JWKSet) Key(kid string, alg string) (interface{}, error) {
+	k, ok := s.keys[kid]
+	if !ok || (k.alg != "" && k.alg != alg) {
+		return nil, ErrJWTKeyNotFound
+	}
+	return k.key, nil
+}
+
+// JWKSFile 从本地文件加载的 JWKS。找不到 kid 时如果文件的修改时间发生了变化则
+// 重新加载，以便在轮换密钥时只需要更新文件。
+type JWKSFile struct {
+	file    string
+	mutex   sync.RWMutex
+	set     *JWKSet
+	modTime time.Time
+}
+
+// NewJWKSFile JWKSFile 的构造函数，立即加载文件
+func NewJWKSFile(file string) (*JWKSFile, error) {
+	f := &JWKSFile{file: file}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload 重新加载文件，加载失败时继续使用原来的密钥
+func (f *JWKSFile) Reload() error {
+	info, err := os.Stat(f.file)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(f.file)
+	if err != nil {
+		return err
+	}
+	set, err := ParseJWKSet(data)
+	if err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.set = set
+	f.modTime = info.ModTime()
+	return nil
+}
+
+func (f *JWKSFile) Key(kid string, alg string) (interface{}, error) {
+
+	f.mutex.RLock()
+	set, modTime := f.set, f.modTime
+	f.mutex.RUnlock()
+
+	key, err := set.Key(kid, alg)
+	if err == nil {
+		return key, nil
+	}
+
+	// 文件没有变化时不重新加载，避免伪造的 kid 导致频繁读取文件
+	if info, e := os.Stat(f.file); e != nil || info.ModTime().Equal(modTime) {
+		return nil, err
+	}
+	if e := f.Reload(); e != nil {
+		return nil, err
+	}
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.set.Key(kid, alg)
+}
+
+// JWTFilter JWT 认证过滤器，从 Authorization 请求头的 Bearer Token 或者 Cookie
+// 中获取 JWT，校验签名、exp、nbf、iss 和 aud。校验通过的 Claims 保存在 WebContext
+// 和请求的 context.Context 中，同时以 sub 作为用户名保存 Principal。
+type JWTFilter struct {
+	keys       JWTKeySource
+	algs       []string
+	issuers    []string
+	audience   []string
+	leeway     time.Duration
+	cookie     string
+	rolesClaim string
+}
+
+// NewJWTFilter JWTFilter 的构造函数，默认支持 HS256、RS256 和 ES256
+func NewJWTFilter(keys JWTKeySource) *JWTFilter {
+	return &JWTFilter{
+		keys:       keys,
+		algs:       []string{JWTAlgHS256, JWTAlgRS256, JWTAlgES256},
+		rolesClaim: "roles",
+	}
+}
+
+// Algorithms 设置允许的签名算法
+func (f *JWTFilter) Algorithms(algs ...string) *JWTFilter {
+	for _, alg := range algs {
+		switch alg {
+		case JWTAlgHS256, JWTAlgRS256, JWTAlgES256:
+		default:
+			panic(fmt.Errorf("unsupported jwt algorithm %q", alg))
+		}
+	}
+	f.algs = algs
+	return f
+}
+
+// Issuer 设置允许的 iss，不设置时不检查
+func (f *JWTFilter) Issuer(issuers ...string) *JWTFilter {
+	f.issuers = issuers
+	return f
+}
+
+// Audience 设置允许的 aud，Token 的 aud 包含其中之一即可，不设置时不检查
+func (f *JWTFilter) Audience(audience ...string) *JWTFilter {
+	f.audience = audience
+	return f
+}
+
+// Leeway 设置检查 exp 和 nbf 时允许的时钟偏差
+func (f *JWTFilter) Leeway(d time.Duration) *JWTFilter {
+	f.leeway = d
+	return f
+}
+
+// Cookie 设置 Authorization 请求头不存在时读取 Token 的 Cookie
+func (f *JWTFilter) Cookie(name string) *JWTFilter {
+	f.cookie = name
+	return f
+}
+
+// RolesClaim 设置 Principal 的角色对应的 Claim，默认为 roles
+func (f *JWTFilter) RolesClaim(name string) *JWTFilter {
+	f.rolesClaim = name
+	return f
+}
+
+func (f *JWTFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	claims, err := f.Verify(f.token(ctx))
+	if err != nil {
+		if err == ErrJWTMissing {
+			ctx.Header(HeaderWWWAuthenticate, "Bearer")
+		} else {
+			desc := strings.TrimPrefix(err.Error(), "jwt: ")
+			ctx.Header(HeaderWWWAuthenticate, `Bearer error="invalid_token", error_description=`+strconv.Quote(desc))
+		}
+		unauthorized(ctx)
+		return
+	}
+
+	ctx.Set(JWTClaimsKey, claims)
+	ctx.Set(PrincipalKey, &Principal{Name: claims.Subject(), Roles: claims.Strings(f.rolesClaim)})
+
+	r := ctx.Request()
+	ctx.SetRequest(r.WithContext(ContextWithJWTClaims(r.Context(), claims)))
+
+	chain.Next(ctx)
+}
+
+// token 返回请求中的 JWT
+func (f *JWTFilter) token(ctx WebContext) string {
+	if auth := ctx.GetHeader(HeaderAuthorization); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+		return ""
+	}
+	if f.cookie != "" {
+		if c, err := ctx.Cookie(f.cookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// Verify 校验 JWT 并返回 Claims
+func (f *JWTFilter) Verify(token string) (JWTClaims, error) {
+
+	if token == "" {
+		return nil, ErrJWTMissing
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, ErrJWTMalformed
+	}
+
+	if !f.allowed(header.Alg) {
+		return nil, ErrJWTAlgorithm
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+
+	key, err := f.keys.Key(header.Kid, header.Alg)
+	if err != nil {
+		return nil, ErrJWTKeyNotFound
+	}
+
+	if err = verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil || claims == nil {
+		return nil, ErrJWTMalformed
+	}
+
+	if err = f.validate(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// allowed 返回是否允许该签名算法
+func (f *JWTFilter) allowed(alg string) bool {
+	for _, a := range f.algs {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// validate 检查 exp、nbf、iss 和 aud
+func (f *JWTFilter) validate(claims JWTClaims, now time.Time) error {
+
+	if exp, ok, err := claims.Time("exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(f.leeway)) {
+		return ErrJWTExpired
+	}
+
+	if nbf, ok, err := claims.Time("nbf"); err != nil {
+		return err
+	} else if ok && now.Add(f.leeway).Before(nbf) {
+		return ErrJWTNotYetValid
+	}
+
+	if len(f.issuers) > 0 && !containsString(f.issuers, claims.Issuer()) {
+		return ErrJWTInvalidIss
+	}
+
+	if len(f.audience) > 0 {
+		found := false
+		for _, aud := range claims.Audience() {
+			if containsString(f.audience, aud) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrJWTInvalidAud
+		}
+	}
+	return nil
+}
+
+// containsString 返回 s 是否在 a 中
+func containsString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJWTPart 解码 JWT 的 header 或者 payload
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// verifyJWTSignature 校验签名，密钥的类型必须和签名算法匹配
+func verifyJWTSignature(alg string, key interface{}, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	switch alg {
+	case JWTAlgHS256:
+		if k, ok := key.([]byte); ok && len(k) >= minHMACKeySize {
+			mac := hmac.New(sha256.New, k)
+			mac.Write([]byte(signed))
+			if hmac.Equal(mac.Sum(nil), sig) {
+				return nil
+			}
+		}
+	case JWTAlgRS256:
+		if k, ok := key.(*rsa.PublicKey); ok {
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil {
+				return nil
+			}
+		}
+	case JWTAlgES256:
+		if k, ok := key.(*ecdsa.PublicKey); ok && len(sig) == 64 {
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(k, sum[:], r, s) {
+				return nil
+			}
+		}
+	}
+	return ErrJWTSignature
+}
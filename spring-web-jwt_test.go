@@ -0,0 +1,216 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+var b64 = base64.RawURLEncoding
+
+// signJWT 生成测试用的 JWT
+func signJWT(t *testing.T, alg string, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+// writeJWKS 把公钥写入 JWKS 文件
+func writeJWKS(t *testing.T, file string, keys ...map[string]string) {
+	b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	if err := ioutil.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJWTFilter(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	file := filepath.Join(dir, "jwks.json")
+	writeJWKS(t, file,
+		map[string]string{"kty": "oct", "kid": "hs", "alg": "HS256", "k": b64.EncodeToString(secret)},
+		map[string]string{"kty": "RSA", "kid": "rs", "n": b64.EncodeToString(rsaKey.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+	)
+	keys, err := SpringWeb.NewJWKSFile(file)
+	assert.Equal(t, err, nil)
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewJWTFilter(keys).Issuer("auth").Audience("api").Leeway(30 * time.Second).Cookie("jwt"))
+
+	c.GetMapping("/me", func(ctx SpringWeb.WebContext) {
+		p := SpringWeb.GetPrincipal(ctx)
+		ctx.String(http.StatusOK, "%s %v %s", p.Name, p.Roles, SpringWeb.JWTClaimsFromContext(ctx.Context()).Subject())
+	})
+	c.GetBinding("/bind", func(ctx context.Context, req *struct{}) string {
+		return SpringWeb.JWTClaimsFromContext(ctx).Subject()
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+	now := time.Now().Unix()
+	claims := map[string]interface{}{"sub": "alice", "iss": "auth", "aud": []string{"web", "api"}, "exp": now + 60, "roles": []string{"admin"}}
+
+	t.Run("algorithms", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+signJWT(t, "HS256", "hs", secret, claims))
+		assert.Equal(t, body, "alice [admin] alice")
+		_, body = doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+signJWT(t, "RS256", "rs", rsaKey, claims))
+		assert.Equal(t, body, "alice [admin] alice")
+		_, body = doRequest(t, http.MethodGet, url+"/bind", "", "Cookie", "jwt="+signJWT(t, "HS256", "hs", secret, claims))
+		assert.Equal(t, strings.Contains(body, `"alice"`), true)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		resp, _ := doRequest(t, http.MethodGet, url+"/me", "")
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+		assert.Equal(t, resp.Header.Get(SpringWeb.HeaderWWWAuthenticate), "Bearer")
+
+		with := func(k string, v interface{}) map[string]interface{} {
+			m := make(map[string]interface{})
+			for n, e := range claims {
+				m[n] = e
+			}
+			m[k] = v
+			return m
+		}
+		for token, desc := range map[string]string{
+			signJWT(t, "HS256", "hs", []byte("wrong"), claims):      "invalid signature",
+			signJWT(t, "HS256", "xx", secret, claims):               "key not found",
+			signJWT(t, "HS256", "rs", secret, claims):               "invalid signature",
+			signJWT(t, "HS256", "hs", secret, with("exp", now-60)):  "token is expired",
+			signJWT(t, "HS256", "hs", secret, with("nbf", now+60)):  "token is not valid yet",
+			signJWT(t, "HS256", "hs", secret, with("iss", "other")): "invalid issuer",
+			signJWT(t, "HS256", "hs", secret, with("aud", "web")):   "invalid audience",
+			signJWT(t, "none", "hs", secret, claims):                "unsupported algorithm",
+			"a.b":                                                   "malformed token",
+		} {
+			resp, _ = doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+token)
+			assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+			assert.Equal(t, resp.Header.Get(SpringWeb.HeaderWWWAuthenticate), `Bearer error="invalid_token", error_description="`+desc+`"`)
+		}
+
+		// 时钟偏差
+		_, body := doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+signJWT(t, "HS256", "hs", secret, with("exp", now-10)))
+		assert.Equal(t, body, "alice [admin] alice")
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		token := signJWT(t, "ES256", "es", ecKey, claims)
+		resp, _ := doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+token)
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+
+		writeJWKS(t, file, map[string]string{"kty": "EC", "kid": "es", "crv": "P-256", "x": b64.EncodeToString(ecKey.X.Bytes()), "y": b64.EncodeToString(ecKey.Y.Bytes())})
+		future := time.Now().Add(time.Minute)
+		_ = os.Chtimes(file, future, future)
+
+		_, body := doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+token)
+		assert.Equal(t, body, "alice [admin] alice")
+		resp, _ = doRequest(t, http.MethodGet, url+"/me", "", "Authorization", "Bearer "+signJWT(t, "HS256", "hs", secret, claims))
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+}
+
+func TestParseJWKSet(t *testing.T) {
+	secret := b64.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	parse := func(keys ...map[string]string) error {
+		b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+		_, err := SpringWeb.ParseJWKSet(b)
+		return err
+	}
+
+	assert.Equal(t, parse(map[string]string{"kty": "oct", "k": secret}), nil)
+	assert.Equal(t, parse(map[string]string{"kty": "oct", "kid": "a", "k": secret}, map[string]string{"kty": "oct", "kid": "b", "k": secret}), nil)
+
+	for desc, keys := range map[string][]map[string]string{
+		"missing k":     {{"kty": "oct", "kid": "a"}},
+		"short k":       {{"kty": "oct", "kid": "a", "k": b64.EncodeToString([]byte("short"))}},
+		"missing kid":   {{"kty": "oct", "kid": "a", "k": secret}, {"kty": "oct", "k": secret}},
+		"duplicate kid": {{"kty": "oct", "kid": "a", "k": secret}, {"kty": "oct", "kid": "a", "k": secret}},
+	} {
+		assert.Equal(t, parse(keys...) != nil, true, desc)
+	}
+}
+
+func TestJWTFilter_EmptyKey(t *testing.T) {
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewJWTFilter(SpringWeb.JWTStaticKey([]byte{})))
+	c.GetMapping("/me", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, SpringWeb.GetPrincipal(ctx).Name)
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	// 任何人都可以用空密钥伪造签名
+	token := signJWT(t, "HS256", "", []byte{}, map[string]interface{}{"sub": "alice"})
+	resp, _ := doRequest(t, http.MethodGet, "http://"+c.Address()+"/me", "", "Authorization", "Bearer "+token)
+	assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+}
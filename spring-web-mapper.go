@@ -23,10 +23,15 @@ import (
 // Mapper 路由映射器
 type Mapper struct {
 	method  uint32   // 方法
+	host    string   // Host 路由，为空时匹配任意 Host
 	path    string   // 路径
+	name    string   // 名称
 	handler Handler  // 处理函数
 	filters []Filter // 过滤器列表
 	swagger *Operation
+
+	roles       []string // 访问需要的角色，拥有其中之一即可
+	permissions []string // 访问需要的权限，需要拥有全部权限
 }
 
 // NewMapper Mapper 的构造函数
@@ -41,7 +46,7 @@ func NewMapper(method uint32, path string, fn Handler, filters []Filter) *Mapper
 
 // Key 返回 Mapper 的标识符
 func (m *Mapper) Key() string {
-	return fmt.Sprintf("0x%.4x@%s", m.method, m.path)
+	return fmt.Sprintf("0x%.4x@%s%s", m.method, m.host, m.path)
 }
 
 // Method 返回 Mapper 的方法
@@ -49,11 +54,27 @@ func (m *Mapper) Method() uint32 {
 	return m.method
 }
 
+// Host 返回 Mapper 的 Host 路由，为空时匹配任意 Host
+func (m *Mapper) Host() string {
+	return m.host
+}
+
 // Path 返回 Mapper 的路径
 func (m *Mapper) Path() string {
 	return m.path
 }
 
+// Name 设置 Mapper 的名称，用于反向生成 URL 地址
+func (m *Mapper) Name(name string) *Mapper {
+	m.name = name
+	return m
+}
+
+// GetName 返回 Mapper 的名称
+func (m *Mapper) GetName() string {
+	return m.name
+}
+
 // Handler 返回 Mapper 的处理函数
 func (m *Mapper) Handler() Handler {
 	return m.handler
@@ -80,3 +101,41 @@ func (m *Mapper) WithSwagger(swagger *Operation) *Mapper {
 	m.swagger = swagger
 	return m
 }
+
+// Secured 设置访问需要的角色，拥有其中之一即可，由 AuthorizationFilter 检查，
+// 路由和容器都没有 AuthorizationFilter 时使用容器默认的 AuthorizationFilter
+func (m *Mapper) Secured(roles ...string) *Mapper {
+	m.roles = append(m.roles, roles...)
+	return m
+}
+
+// GetRoles 返回访问需要的角色
+func (m *Mapper) GetRoles() []string {
+	return m.roles
+}
+
+// RequirePermission 设置访问需要的权限，需要拥有全部权限，由 AuthorizationFilter 检查
+func (m *Mapper) RequirePermission(permissions ...string) *Mapper {
+	m.permissions = append(m.permissions, permissions...)
+	return m
+}
+
+// GetPermissions 返回访问需要的权限
+func (m *Mapper) GetPermissions() []string {
+	return m.permissions
+}
+
+// isSecured 返回是否设置了访问需要的角色或者权限
+func (m *Mapper) isSecured() bool {
+	return len(m.roles) > 0 || len(m.permissions) > 0
+}
+
+// withMethod 返回只包含指定方法的 Mapper 副本，Mapper 注册之后可能正在被路由表
+// 并发读取，所以不能直接修改
+func (m *Mapper) withMethod(method uint32) *Mapper {
+	r := *m
+	r.method = method
+	r.roles = append([]string(nil), m.roles...)
+	r.permissions = append([]string(nil), m.permissions...)
+	return &r
+}
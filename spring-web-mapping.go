@@ -16,6 +16,17 @@
 
 package SpringWeb
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/go-spring-logger"
+)
+
 // UrlRegister 路由注册接口
 type UrlRegister interface {
 
@@ -133,21 +144,56 @@ func (r *defaultUrlRegister) DeleteBinding(path string, fn interface{}, filters
 type WebMapping interface {
 	UrlRegister
 
-	// Mappers 返回映射器列表
+	// Mappers 返回映射器列表的副本
 	Mappers() map[string]*Mapper
 
-	// AddMapper 添加一个 Mapper
+	// AddMapper 添加一个 Mapper，和已有的 Mapper 冲突时按照冲突策略处理，
+	// 容器启动之后也可以调用，新的路由立即生效。
 	AddMapper(m *Mapper) *Mapper
 
+	// RemoveMapper 删除一个 Mapper，容器启动之后也可以调用，返回是否删除成功
+	RemoveMapper(m *Mapper) bool
+
+	// OnChange 注册路由表发生变化时的回调函数，回调函数在路由表的锁之外执行
+	OnChange(fn func())
+
 	// Route 返回和 Mapping 绑定的路由分组
 	Route(basePath string, filters ...Filter) *Router
+
+	// Host 返回只匹配指定 Host 的路由分组，pattern 支持 {name} 形式的参数和
+	// 开头的 * 通配符，例如 {tenant}.example.com、*.example.com。
+	Host(pattern string, filters ...Filter) *Router
+
+	// URLFor 根据 Mapper 的名称生成 URL 地址，params 是交替出现的参数名和参数值，
+	// 路径中不存在的参数被添加到查询字符串中。
+	URLFor(name string, params ...interface{}) (string, error)
+
+	// ConflictPolicy 返回路由冲突时的处理策略
+	ConflictPolicy() ConflictPolicyEnum
+
+	// SetConflictPolicy 设置路由冲突时的处理策略
+	SetConflictPolicy(policy ConflictPolicyEnum)
 }
 
-// defaultWebMapping 路由表的默认实现
+// ConflictPolicyEnum 路由冲突时的处理策略。当两个 Mapper 的 HTTP 方法有交集，
+// 并且路径完全相同或者只有参数名不同 (例如 /user/{id} 和 /user/{name}) 时，
+// 认为这两个 Mapper 存在冲突。
+type ConflictPolicyEnum int
+
+const (
+	WarnOnConflict     = ConflictPolicyEnum(0) // 打印警告日志，然后按照后注册者优先处理
+	PanicOnConflict    = ConflictPolicyEnum(1) // 注册时直接 panic
+	LastWinsOnConflict = ConflictPolicyEnum(2) // 后注册者优先，不打印日志
+)
+
+// defaultWebMapping 路由表的默认实现，并发安全
 type defaultWebMapping struct {
 	UrlRegister
 
-	mappers map[string]*Mapper
+	mutex     sync.RWMutex
+	mappers   map[string]*Mapper
+	policy    ConflictPolicyEnum
+	listeners []func()
 }
 
 // NewDefaultWebMapping defaultWebMapping 的构造函数
@@ -158,24 +204,234 @@ func NewDefaultWebMapping() *defaultWebMapping {
 	return m
 }
 
-// Mappers 返回映射器列表
+// Mappers 返回映射器列表的副本
 func (w *defaultWebMapping) Mappers() map[string]*Mapper {
-	return w.mappers
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	r := make(map[string]*Mapper, len(w.mappers))
+	for k, m := range w.mappers {
+		r[k] = m
+	}
+	return r
 }
 
 // AddMapper 添加一个 Mapper
 func (w *defaultWebMapping) AddMapper(m *Mapper) *Mapper {
+	w.mutex.Lock()
+	w.resolveConflict(m)
 	w.mappers[m.Key()] = m
+	w.mutex.Unlock()
+	w.notify()
 	return m
 }
 
+// RemoveMapper 删除一个 Mapper，返回是否删除成功
+func (w *defaultWebMapping) RemoveMapper(m *Mapper) bool {
+	w.mutex.Lock()
+	ok := w.mappers[m.Key()] == m
+	if ok {
+		delete(w.mappers, m.Key())
+	}
+	w.mutex.Unlock()
+	if ok {
+		w.notify()
+	}
+	return ok
+}
+
+// OnChange 注册路由表发生变化时的回调函数
+func (w *defaultWebMapping) OnChange(fn func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// notify 通知路由表发生了变化
+func (w *defaultWebMapping) notify() {
+	w.mutex.RLock()
+	listeners := w.listeners
+	w.mutex.RUnlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// ConflictPolicy 返回路由冲突时的处理策略
+func (w *defaultWebMapping) ConflictPolicy() ConflictPolicyEnum {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.policy
+}
+
+// SetConflictPolicy 设置路由冲突时的处理策略
+func (w *defaultWebMapping) SetConflictPolicy(policy ConflictPolicyEnum) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.policy = policy
+}
+
+// resolveConflict 检测并处理 m 和已注册的 Mapper 之间的冲突，后注册者优先时
+// 使用去掉重叠 HTTP 方法的副本替换先注册的 Mapper，没有剩余方法的 Mapper 将被
+// 删除。注意替换之后用户持有的旧 Mapper 不再有效，需要通过 Mappers 获取新的副本。
+func (w *defaultWebMapping) resolveConflict(m *Mapper) {
+
+	var keys []string
+	shape := hostShape(m.Host()) + pathShape(m.Path())
+	for key, old := range w.mappers {
+		if old != m && old.Method()&m.Method() != 0 && hostShape(old.Host())+pathShape(old.Path()) == shape {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		old := w.mappers[key]
+
+		switch w.policy {
+		case PanicOnConflict:
+			panic(fmt.Errorf("route %s conflicts with %s", mapperString(m), mapperString(old)))
+		case WarnOnConflict:
+			SpringLogger.Warnf("route %s conflicts with %s, the latter is overridden", mapperString(m), mapperString(old))
+		}
+
+		delete(w.mappers, key)
+		if method := old.Method() &^ m.Method(); method != 0 {
+			narrowed := old.withMethod(method)
+			w.mappers[narrowed.Key()] = narrowed
+		}
+	}
+}
+
+// pathShape 返回忽略参数名之后的路径 (保留约束条件)，用于检测有歧义的路由
+func pathShape(path string) string {
+	var sb strings.Builder
+	for _, seg := range parsePath(path) {
+		switch seg.kind {
+		case namedSegment:
+			sb.WriteString("/{" + seg.constraint + "}")
+		case wildCardSegment:
+			sb.WriteString("/*")
+		default:
+			sb.WriteString("/" + seg.name)
+		}
+	}
+	return sb.String()
+}
+
+// hostShape 返回忽略参数名之后的 Host 路由
+func hostShape(host string) string {
+	if host == "" {
+		return ""
+	}
+	return getHostPattern(host).shape()
+}
+
+// mapperString 返回 Mapper 的方法、路径以及处理函数的位置
+func mapperString(m *Mapper) string {
+	s := fmt.Sprintf("%v %s%s", GetMethod(m.Method()), m.Host(), m.Path())
+	if m.Handler() != nil {
+		file, line, fnName := m.Handler().FileLine()
+		s += fmt.Sprintf(" (%s:%d %s)", file, line, fnName)
+	}
+	return s
+}
+
 // Route 返回和 Mapping 绑定的路由分组
 func (w *defaultWebMapping) Route(basePath string, filters ...Filter) *Router {
 	return routerWithMapping(w, basePath, filters)
 }
 
+// Host 返回只匹配指定 Host 的路由分组
+func (w *defaultWebMapping) Host(pattern string, filters ...Filter) *Router {
+	getHostPattern(pattern) // 尽早发现格式错误
+	r := routerWithMapping(w, "", filters)
+	r.host = pattern
+	return r
+}
+
+// URLFor 根据 Mapper 的名称生成 URL 地址
+func (w *defaultWebMapping) URLFor(name string, params ...interface{}) (string, error) {
+
+	var found []*Mapper
+	for _, m := range w.Mappers() {
+		if m.GetName() == name {
+			found = append(found, m)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("route named %s not found", name)
+	case 1:
+		return buildURL(found[0].Path(), params...)
+	default:
+		return "", fmt.Errorf("route named %s is ambiguous", name)
+	}
+}
+
 func (w *defaultWebMapping) request(method uint32, path string, fn Handler, filters []Filter) *Mapper {
-	m := NewMapper(method, path, fn, filters)
-	w.mappers[m.Key()] = m
-	return m
+	return w.AddMapper(NewMapper(method, CleanPath(path), fn, filters))
+}
+
+// buildURL 使用 params 替换路由地址中的命名参数和通配符，params 是交替出现的
+// 参数名和参数值，多余的参数被添加到查询字符串中。
+func buildURL(path string, params ...interface{}) (string, error) {
+
+	if len(params)%2 != 0 {
+		return "", errors.New("params should be name-value pairs")
+	}
+
+	values := make(map[string]string)
+	var names []string
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("param name %v should be a string", params[i])
+		}
+		values[key] = fmt.Sprint(params[i+1])
+		names = append(names, key)
+	}
+
+	used := make(map[string]bool)
+	var sb strings.Builder
+
+	for _, seg := range parsePath(path) {
+		switch seg.kind {
+		case namedSegment:
+			v, ok := values[seg.name]
+			if !ok || v == "" {
+				return "", fmt.Errorf("missing path param %s", seg.name)
+			}
+			if seg.constraint != "" && !getConstraint(seg.constraint).match(v) {
+				return "", fmt.Errorf("path param %s=%s doesn't match %s", seg.name, v, seg.constraint)
+			}
+			used[seg.name] = true
+			sb.WriteString("/" + url.PathEscape(v))
+		case wildCardSegment:
+			name := seg.name
+			if _, ok := values[name]; !ok || name == "" {
+				name = "*"
+			}
+			used[name] = true
+			ss := strings.Split(values[name], "/")
+			for i, s := range ss {
+				ss[i] = url.PathEscape(s)
+			}
+			sb.WriteString("/" + strings.Join(ss, "/"))
+		default:
+			sb.WriteString("/" + seg.name)
+		}
+	}
+
+	query := url.Values{}
+	for _, name := range names {
+		if !used[name] {
+			query.Add(name, values[name])
+		}
+	}
+
+	if len(query) > 0 {
+		sb.WriteString("?" + query.Encode())
+	}
+	return sb.String(), nil
 }
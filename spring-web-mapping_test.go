@@ -0,0 +1,190 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func emptyHandler(ctx SpringWeb.WebContext) {}
+
+func TestWebMapping_Conflict(t *testing.T) {
+
+	t.Run("panic", func(t *testing.T) {
+		m := SpringWeb.NewDefaultWebMapping()
+		m.SetConflictPolicy(SpringWeb.PanicOnConflict)
+		m.GetMapping("/user/{id}", emptyHandler)
+		m.PostMapping("/user/{name}", emptyHandler)
+
+		defer func() {
+			err := fmt.Sprint(recover())
+			assert.Equal(t, strings.Contains(err, "[GET] /user/{name}"), true)
+			assert.Equal(t, strings.Contains(err, "[GET] /user/{id}"), true)
+			assert.Equal(t, strings.Contains(err, "spring-web-mapping_test.go"), true)
+		}()
+
+		m.GetMapping("/user/{name}", emptyHandler)
+		t.Fatal("should panic")
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		m := SpringWeb.NewDefaultWebMapping()
+		m.SetConflictPolicy(SpringWeb.PanicOnConflict)
+		m.GetMapping("/user", emptyHandler)
+
+		defer func() {
+			assert.Equal(t, recover() != nil, true)
+		}()
+
+		m.AddMapper(SpringWeb.NewMapper(SpringWeb.MethodGet, "/user", nil, nil))
+		t.Fatal("should panic")
+	})
+
+	t.Run("last wins", func(t *testing.T) {
+		m := SpringWeb.NewDefaultWebMapping()
+		m.SetConflictPolicy(SpringWeb.LastWinsOnConflict)
+		all := m.Request(SpringWeb.MethodGetPost, "/user/{id}", SpringWeb.FUNC(emptyHandler))
+		get := m.GetMapping("/user/:name", emptyHandler)
+		assert.Equal(t, len(m.Mappers()), 2)
+		assert.Equal(t, m.Mappers()[get.Key()], get)
+
+		// 先注册的 Mapper 被只保留剩余方法的副本替换，原对象保持不变
+		assert.Equal(t, all.Method(), uint32(SpringWeb.MethodGetPost))
+		assert.Equal(t, m.RemoveMapper(all), false)
+		narrowed := m.Mappers()["0x0004@/user/{id}"]
+		assert.Equal(t, narrowed.Method(), uint32(SpringWeb.MethodPost))
+		assert.Equal(t, narrowed.Path(), all.Path())
+		assert.Equal(t, m.RemoveMapper(narrowed), true)
+		assert.Equal(t, len(m.Mappers()), 1)
+
+		all = m.Request(SpringWeb.MethodGetPost, "/user/{id}", SpringWeb.FUNC(emptyHandler))
+		post := m.PostMapping("/user/{id}", emptyHandler)
+		assert.Equal(t, len(m.Mappers()), 2)
+		assert.Equal(t, m.Mappers()[post.Key()], post)
+		assert.Equal(t, m.Mappers()["0x0001@/user/{id}"].Method(), uint32(SpringWeb.MethodGet))
+	})
+
+	t.Run("no conflict", func(t *testing.T) {
+		m := SpringWeb.NewDefaultWebMapping()
+		m.SetConflictPolicy(SpringWeb.PanicOnConflict)
+		m.GetMapping("/user/{id}", emptyHandler)
+		m.GetMapping("/user/me", emptyHandler)
+		m.PostMapping("/user/{name}", emptyHandler)
+		m.GetMapping("/user/{id}/*", emptyHandler)
+		assert.Equal(t, len(m.Mappers()), 4)
+	})
+
+	t.Run("host", func(t *testing.T) {
+		m := SpringWeb.NewDefaultWebMapping()
+		m.SetConflictPolicy(SpringWeb.PanicOnConflict)
+		m.GetMapping("/user", emptyHandler)
+		m.Host("api.example.com").GetMapping("/user", emptyHandler)
+		m.Host("{tenant}.example.com").GetMapping("/user", emptyHandler)
+		assert.Equal(t, len(m.Mappers()), 3)
+
+		defer func() {
+			err := fmt.Sprint(recover())
+			assert.Equal(t, strings.Contains(err, "{name}.example.com/user"), true)
+		}()
+
+		m.Host("{name}.example.com").GetMapping("/user", emptyHandler)
+		t.Fatal("should panic")
+	})
+
+	t.Run("bad host", func(t *testing.T) {
+		defer func() {
+			assert.Equal(t, recover() != nil, true)
+		}()
+		SpringWeb.NewDefaultWebMapping().Host("api.*.com")
+		t.Fatal("should panic")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		m := SpringWeb.NewDefaultWebMapping()
+		changed := 0
+		m.OnChange(func() { changed++ })
+		get := m.GetMapping("/user", emptyHandler)
+		assert.Equal(t, m.RemoveMapper(get), true)
+		assert.Equal(t, m.RemoveMapper(get), false)
+		assert.Equal(t, len(m.Mappers()), 0)
+		assert.Equal(t, changed, 2)
+	})
+
+	t.Run("router", func(t *testing.T) {
+		c := SpringWeb.NewBaseWebContainer(SpringWeb.ContainerConfig{})
+		c.SetConflictPolicy(SpringWeb.PanicOnConflict)
+		c.GetMapping("/api/user", emptyHandler)
+
+		r := SpringWeb.NewRouter("/api")
+		r.GetMapping("/user", emptyHandler)
+
+		defer func() {
+			assert.Equal(t, recover() != nil, true)
+		}()
+
+		c.AddRouter(r)
+		t.Fatal("should panic")
+	})
+}
+
+func TestWebMapping_URLFor(t *testing.T) {
+
+	c := SpringWeb.NewBaseWebContainer(SpringWeb.ContainerConfig{})
+	c.GetMapping("/user/{id:int}", emptyHandler).Name("user")
+	c.GetMapping("/static/{*:file}", emptyHandler).Name("static")
+	c.GetMapping("/search/:keyword", emptyHandler).Name("search")
+
+	r := c.Route("/api/v1")
+	r.GetMapping("/books/*", emptyHandler).Name("books")
+
+	t.Run("named", func(t *testing.T) {
+		u, err := c.URLFor("user", "id", 42)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, u, "/user/42")
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		u, err := c.URLFor("search", "keyword", "a b/c", "page", 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, u, "/search/a%20b%2Fc?page=2")
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		u, err := c.URLFor("static", "file", "js/a b.js")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, u, "/static/js/a%20b.js")
+		u, err = r.URLFor("books", "*", "go/spring")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, u, "/api/v1/books/go/spring")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := c.URLFor("none")
+		assert.Equal(t, err.Error(), "route named none not found")
+		_, err = c.URLFor("user", "id", "abc")
+		assert.Equal(t, err.Error(), "path param id=abc doesn't match int")
+		_, err = c.URLFor("user")
+		assert.Equal(t, err.Error(), "missing path param id")
+		_, err = c.URLFor("user", "id")
+		assert.Equal(t, err.Error(), "params should be name-value pairs")
+	})
+}
@@ -18,6 +18,7 @@ package SpringWeb
 
 import (
 	"net/http"
+	"strings"
 )
 
 const (
@@ -46,13 +47,56 @@ var methods = map[uint32]string{
 	MethodTrace:   http.MethodTrace,
 }
 
+// methodOrder 保证 GetMethod 的返回值有确定的顺序
+var methodOrder = []uint32{
+	MethodGet,
+	MethodHead,
+	MethodPost,
+	MethodPut,
+	MethodPatch,
+	MethodDelete,
+	MethodConnect,
+	MethodOptions,
+	MethodTrace,
+}
+
 // GetMethod 返回 method 对应的 HTTP 方法
 func GetMethod(method uint32) []string {
 	var r []string
-	for k, v := range methods {
+	for _, k := range methodOrder {
 		if method&k == k {
-			r = append(r, v)
+			r = append(r, methods[k])
 		}
 	}
 	return r
 }
+
+// methodBit 返回 HTTP 方法对应的掩码，未知的方法返回 0
+func methodBit(method string) uint32 {
+	switch method {
+	case http.MethodGet:
+		return MethodGet
+	case http.MethodHead:
+		return MethodHead
+	case http.MethodPost:
+		return MethodPost
+	case http.MethodPut:
+		return MethodPut
+	case http.MethodPatch:
+		return MethodPatch
+	case http.MethodDelete:
+		return MethodDelete
+	case http.MethodConnect:
+		return MethodConnect
+	case http.MethodOptions:
+		return MethodOptions
+	case http.MethodTrace:
+		return MethodTrace
+	}
+	return 0
+}
+
+// allowHeader 返回 method 对应的 Allow 响应头
+func allowHeader(method uint32) string {
+	return strings.Join(GetMethod(method), ", ")
+}
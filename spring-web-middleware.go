@@ -33,7 +33,7 @@ package SpringWeb
 // echo: https://github.com/labstack/echo/blob/master/middleware/redirect.go
 // gin:
 
-// request_id
+// request_id (RequestIDFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/request_id.go
 // gin: https://github.com/gin-contrib/requestid/blob/master/requestid.go
 
@@ -41,11 +41,11 @@ package SpringWeb
 // echo: https://github.com/labstack/echo/blob/master/middleware/rewrite.go
 // gin:
 
-// basic_auth
+// basic_auth (BasicAuthFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/basic_auth.go
 // gin:
 
-// key_auth
+// key_auth (APIKeyFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/key_auth.go
 // gin:
 
@@ -53,15 +53,15 @@ package SpringWeb
 // echo: https://github.com/labstack/echo/blob/master/middleware/body_dump.go
 // gin:
 
-// body_limit (413 - Request Entity Too Large)
+// body_limit (413 - Request Entity Too Large, BodyLimitFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/body_limit.go
 // gin: https://github.com/gin-contrib/size/blob/master/size.go
 
-// compress (gzip)
+// compress (gzip, CompressFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/compress.go
 // gin: https://github.com/gin-contrib/gzip/blob/master/gzip.go
 
-// cors (Cross-Origin Resource Sharing)
+// cors (Cross-Origin Resource Sharing, CORSFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/cors.go
 // gin: https://github.com/gin-contrib/cors/blob/master/cors.go
 
@@ -73,7 +73,7 @@ package SpringWeb
 // echo: https://github.com/labstack/echo/blob/master/middleware/secure.go
 // gin:
 
-// jwt (JSON Web Token)
+// jwt (JSON Web Token, JWTFilter)
 // echo: https://github.com/labstack/echo/blob/master/middleware/jwt.go
 // gin: https://github.com/appleboy/gin-jwt/blob/master/auth_jwt.go
 
@@ -89,7 +89,7 @@ package SpringWeb
 // echo: https://github.com/labstack/echo/blob/master/middleware/static.go
 // gin: https://github.com/gin-contrib/static/blob/master/static.go
 
-// casbin
+// casbin (AuthorizationFilter, CasbinPolicy)
 // echo: https://github.com/labstack/echo-contrib/blob/master/casbin/casbin.go
 // gin: https://github.com/gin-contrib/authz/blob/master/authz.go
 
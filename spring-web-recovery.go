@@ -0,0 +1,251 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-spring/go-spring-error"
+)
+
+var defaultRecoveryFilter = NewRecoveryFilter()
+
+// PanicInfo 捕获的 panic 信息
+type PanicInfo struct {
+	Value  interface{}     // panic 的值
+	Stack  []byte          // 发生 panic 时的调用栈
+	Frames []runtime.Frame // 调用栈中发生 panic 的位置及其调用者，不包括 runtime 包
+}
+
+// Error 返回 panic 的值对应的错误
+func (p *PanicInfo) Error() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return errors.New(fmt.Sprint(p.Value))
+}
+
+// RecoveryHandler 自定义的 panic 处理函数
+type RecoveryHandler func(ctx WebContext, p *PanicInfo)
+
+// RecoveryFilter 恢复过滤器，捕获 panic 及其调用栈并打印日志，然后返回 500
+// 响应。API 路由返回 RpcResult 格式的 JSON，开发模式下返回带有源码片段的 HTML
+// 错误页面，其他情况返回纯文本。响应头已经写出时不再写入任何内容。
+type RecoveryFilter struct {
+	handler RecoveryHandler
+	api     *RouteTree // API 路由，BIND 形式的路由总是 API 路由
+	devMode bool
+}
+
+// NewRecoveryFilter RecoveryFilter 的构造函数
+func NewRecoveryFilter() *RecoveryFilter {
+	return &RecoveryFilter{}
+}
+
+// WithHandler 设置自定义的 panic 处理函数，设置之后由其负责写入响应
+func (f *RecoveryFilter) WithHandler(handler RecoveryHandler) *RecoveryFilter {
+	f.handler = handler
+	return f
+}
+
+// API 添加返回 JSON 格式错误信息的路由，使用和 ConditionalFilter 相同的路径语法
+func (f *RecoveryFilter) API(patterns ...string) *RecoveryFilter {
+	f.api = addFilterPatterns(f.api, patterns)
+	return f
+}
+
+// DevMode 设置是否开启开发模式，开发模式下返回 HTML 格式的错误页面，其中包括
+// 调用栈和源码片段，不能在生产环境中开启
+func (f *RecoveryFilter) DevMode(enable bool) *RecoveryFilter {
+	f.devMode = enable
+	return f
+}
+
+// Phase 恢复过滤器在路由匹配之前执行
+func (f *RecoveryFilter) Phase() FilterPhase {
+	return PreRoutingPhase
+}
+
+// Order 恢复过滤器位于日志过滤器之后
+func (f *RecoveryFilter) Order() int {
+	return RecoveryFilterOrder
+}
+
+func (f *RecoveryFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		// 标准库约定的中止请求的方式，交给 net/http 处理
+		if r == http.ErrAbortHandler {
+			panic(r)
+		}
+
+		p := &PanicInfo{Value: r, Stack: debug.Stack(), Frames: panicFrames()}
+		ctx.LogError("[PANIC RECOVER] ", r, "\n", string(p.Stack))
+
+		if f.handler != nil {
+			f.handler(ctx, p)
+			return
+		}
+
+		if w, ok := ctx.ResponseWriter().(ResponseWriter); ok && w.Written() {
+			return
+		}
+
+		code := http.StatusInternalServerError
+		switch {
+		case f.isAPI(ctx):
+			ctx.JSON(code, SpringError.ERROR.Error(p.Error()))
+		case f.devMode:
+			ctx.HTMLBlob(code, devErrorPage(ctx, p))
+		default:
+			ctx.String(code, "%s", http.StatusText(code))
+		}
+	}()
+
+	chain.Next(ctx)
+}
+
+// isAPI 返回当前请求是否是 API 路由
+func (f *RecoveryFilter) isAPI(ctx WebContext) bool {
+	if _, ok := ctx.Handler().(*bindHandler); ok {
+		return true
+	}
+	path := ctx.Path()
+	return f.api != nil && path != "" && f.api.Allowed(path) != 0
+}
+
+// panicFrames 返回发生 panic 的位置及其调用者，只能在 recover 所在的函数中调用
+func panicFrames() []runtime.Frame {
+
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	// 跳过 recover 所在的函数和 runtime.gopanic 等函数
+	var r []runtime.Frame
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "runtime.") {
+			panicking = true
+		} else if panicking {
+			r = append(r, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return r
+}
+
+// sourceLine 源码中的一行
+type sourceLine struct {
+	Num     int
+	Text    string
+	Current bool
+}
+
+// sourceSnippet 源码片段
+type sourceSnippet struct {
+	Title string
+	File  string
+	Line  int
+	Lines []sourceLine
+}
+
+// readSnippet 读取 file 文件 line 行前后的源码，读取失败时返回 nil
+func readSnippet(title string, file string, line int) *sourceSnippet {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(b), "\n")
+	s := &sourceSnippet{Title: title, File: file, Line: line}
+	for i := line - 5; i <= line+5; i++ {
+		if i > 0 && i <= len(lines) {
+			s.Lines = append(s.Lines, sourceLine{Num: i, Text: lines[i-1], Current: i == line})
+		}
+	}
+	return s
+}
+
+var devErrorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>500 {{.Value}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+.current { background: #ffdce0; }
+</style>
+</head>
+<body>
+<h1>panic: {{.Value}}</h1>
+<p>{{.Method}} {{.URI}}</p>
+{{range .Snippets}}
+<h2>{{.Title}}: {{.File}}:{{.Line}}</h2>
+<pre>{{range .Lines}}<span{{if .Current}} class="current"{{end}}>{{printf "%5d" .Num}}  {{.Text}}</span>
+{{end}}</pre>
+{{end}}
+<h2>Stack</h2>
+<pre>{{.Stack}}</pre>
+</body>
+</html>
+`))
+
+// devErrorPage 返回开发模式下的 HTML 错误页面
+func devErrorPage(ctx WebContext, p *PanicInfo) []byte {
+
+	var snippets []*sourceSnippet
+	if len(p.Frames) > 0 {
+		frame := p.Frames[0]
+		if s := readSnippet("panic", frame.File, frame.Line); s != nil {
+			snippets = append(snippets, s)
+		}
+	}
+	if h := ctx.Handler(); h != nil {
+		file, line, fnName := h.FileLine()
+		if s := readSnippet("handler "+fnName, file, line); s != nil {
+			snippets = append(snippets, s)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := devErrorTemplate.Execute(&buf, map[string]interface{}{
+		"Value":    fmt.Sprint(p.Value),
+		"Method":   ctx.Request().Method,
+		"URI":      ctx.Request().RequestURI,
+		"Snippets": snippets,
+		"Stack":    string(p.Stack),
+	})
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return buf.Bytes()
+}
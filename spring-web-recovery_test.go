@@ -0,0 +1,118 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestRecoveryFilter(t *testing.T) {
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1", ImplicitHead: true})
+	c.SetRecoveryFilter(SpringWeb.NewRecoveryFilter().API("/api/*"))
+
+	c.GetMapping("/panic", func(ctx SpringWeb.WebContext) {
+		panic("boom")
+	})
+	c.GetMapping("/api/panic", func(ctx SpringWeb.WebContext) {
+		panic("boom")
+	})
+	c.GetMapping("/written", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusAccepted, "partial")
+		panic("boom")
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("text", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/panic", "")
+		assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+		assert.Equal(t, body, http.StatusText(http.StatusInternalServerError))
+	})
+
+	t.Run("api", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/api/panic", "")
+		assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+		assert.Equal(t, body, `{"code":-1,"msg":"ERROR","err":"boom"}`)
+	})
+
+	t.Run("written", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/written", "")
+		assert.Equal(t, resp.StatusCode, http.StatusAccepted)
+		assert.Equal(t, body, "partial")
+	})
+
+	t.Run("head", func(t *testing.T) {
+		for _, path := range []string{"/panic", "/api/panic", "/written"} {
+			get, _ := doRequest(t, http.MethodGet, url+path, "")
+			head, body := doRequest(t, http.MethodHead, url+path, "")
+			assert.Equal(t, head.StatusCode, get.StatusCode, path)
+			assert.Equal(t, body, "")
+		}
+	})
+}
+
+func TestRecoveryFilter_DevMode(t *testing.T) {
+
+	var info *SpringWeb.PanicInfo
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.SetRecoveryFilter(SpringWeb.NewRecoveryFilter().DevMode(true))
+
+	c.GetMapping("/panic", func(ctx SpringWeb.WebContext) {
+		panic("<boom>")
+	})
+
+	custom := SpringWeb.NewRecoveryFilter().WithHandler(func(ctx SpringWeb.WebContext, p *SpringWeb.PanicInfo) {
+		info = p
+		ctx.String(http.StatusServiceUnavailable, "custom")
+	})
+	c.Route("/custom", custom).GetMapping("/panic", func(ctx SpringWeb.WebContext) {
+		panic("boom")
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("dev page", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/panic", "")
+		assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+		assert.Equal(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html"), true)
+		assert.Equal(t, strings.Contains(body, "panic: &lt;boom&gt;"), true)
+		assert.Equal(t, strings.Contains(body, "spring-web-recovery_test.go"), true)
+		assert.Equal(t, strings.Contains(body, `panic(&#34;&lt;boom&gt;&#34;)`), true)
+	})
+
+	t.Run("custom handler", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/custom/panic", "")
+		assert.Equal(t, resp.StatusCode, http.StatusServiceUnavailable)
+		assert.Equal(t, body, "custom")
+		assert.Equal(t, info.Value, "boom")
+		assert.Equal(t, strings.HasSuffix(info.Frames[0].File, "spring-web-recovery_test.go"), true)
+		assert.Equal(t, len(info.Stack) > 0, true)
+	})
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/go-spring/go-spring-logger"
+)
+
+// RequestIDKey WebContext 中保存请求 ID 的 Key
+const RequestIDKey = "@RequestID"
+
+// RequestIDFilterOrder RequestIDFilter 的顺序值，在日志过滤器之前执行
+const RequestIDFilterOrder = -2500
+
+// maxRequestIDLength 请求 ID 的最大长度，超过时重新生成
+const maxRequestIDLength = 128
+
+// requestIDContextKey context.Context 中保存请求 ID 的 Key
+type requestIDContextKey struct{}
+
+// RequestID 返回当前请求的 ID，没有启用 RequestIDFilter 时返回空字符串
+func RequestID(ctx WebContext) string {
+	id, _ := ctx.Get(RequestIDKey).(string)
+	return id
+}
+
+// RequestIDFromContext 返回 context.Context 中保存的请求 ID，可以在 BIND 形式的
+// 处理函数中使用
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// ContextWithRequestID 返回保存了请求 ID 的 context.Context，用于向下游传递
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// NewRequestID 生成 32 位十六进制的随机请求 ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFilter 请求 ID 过滤器，读取请求头中的 ID，没有或者不合法时生成新的
+// ID，然后写入响应头，并且保存到 WebContext 和 context.Context 中。之后 WebContext
+// 输出的日志 (包括 Logger 返回的 StdLogger) 都会带上 request_id=xxx 前缀。
+type RequestIDFilter struct {
+	header    string
+	generator func() string
+}
+
+// NewRequestIDFilter RequestIDFilter 的构造函数，默认使用 X-Request-ID 请求头
+func NewRequestIDFilter() *RequestIDFilter {
+	return &RequestIDFilter{header: HeaderXRequestID, generator: NewRequestID}
+}
+
+// WithHeader 设置读取和写入请求 ID 的 Header
+func (f *RequestIDFilter) WithHeader(header string) *RequestIDFilter {
+	f.header = header
+	return f
+}
+
+// WithGenerator 设置请求 ID 的生成函数
+func (f *RequestIDFilter) WithGenerator(generator func() string) *RequestIDFilter {
+	f.generator = generator
+	return f
+}
+
+// Phase 请求 ID 过滤器在路由匹配之前执行
+func (f *RequestIDFilter) Phase() FilterPhase {
+	return PreRoutingPhase
+}
+
+// Order 请求 ID 过滤器在日志过滤器之前执行，以便访问日志带上请求 ID
+func (f *RequestIDFilter) Order() int {
+	return RequestIDFilterOrder
+}
+
+func (f *RequestIDFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	id := r.Header.Get(f.header)
+	if !validRequestID(id) {
+		id = f.generator()
+		r.Header.Set(f.header, id)
+	}
+
+	ctx.Header(f.header, id)
+	ctx.Set(RequestIDKey, id)
+	ctx.SetRequest(r.WithContext(ContextWithRequestID(r.Context(), id)))
+
+	chain.Next(ctx)
+}
+
+// validRequestID 请求 ID 不能为空、不能过长，只能包含可见的 ASCII 字符
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newLoggerContext 返回请求使用的 LoggerContext，context.Context 中保存了请求 ID
+// 时每行日志都带上请求 ID
+func newLoggerContext(ctx context.Context) SpringLogger.LoggerContext {
+	c := SpringLogger.NewDefaultLoggerContext(ctx)
+	if id := RequestIDFromContext(ctx); id != "" {
+		prefix := "request_id=" + id + " "
+		return &requestIDLoggerContext{
+			DefaultLoggerContext: c,
+			prefix:               prefix,
+			format:               strings.Replace(prefix, "%", "%%", -1),
+		}
+	}
+	return c
+}
+
+// requestIDLoggerContext 在每行日志前面加上请求 ID 的 LoggerContext。设置了
+// SpringLogger.Logger 时直接调用它返回的 StdLogger，调用栈的深度和
+// DefaultLoggerContext 相同，不影响自定义 Logger 获取调用者的位置。
+type requestIDLoggerContext struct {
+	*SpringLogger.DefaultLoggerContext
+	prefix string
+	format string // 转义之后可以用在格式字符串中的 prefix
+}
+
+// logger 返回实际输出日志的 StdLogger
+func (c *requestIDLoggerContext) logger(tags ...string) SpringLogger.StdLogger {
+	if SpringLogger.Logger != nil {
+		return SpringLogger.Logger(c.Context(), tags...)
+	}
+	return c.DefaultLoggerContext.Logger(tags...)
+}
+
+func (c *requestIDLoggerContext) args(args []interface{}) []interface{} {
+	return append([]interface{}{c.prefix}, args...)
+}
+
+// Logger 返回带有请求 ID 前缀的 StdLogger
+func (c *requestIDLoggerContext) Logger(tags ...string) SpringLogger.StdLogger {
+	return &requestIDLogger{l: c.logger(tags...), c: c}
+}
+
+func (c *requestIDLoggerContext) LogTrace(args ...interface{}) {
+	c.logger().Trace(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogTracef(format string, args ...interface{}) {
+	c.logger().Tracef(c.format+format, args...)
+}
+
+func (c *requestIDLoggerContext) LogDebug(args ...interface{}) {
+	c.logger().Debug(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogDebugf(format string, args ...interface{}) {
+	c.logger().Debugf(c.format+format, args...)
+}
+
+func (c *requestIDLoggerContext) LogInfo(args ...interface{}) {
+	c.logger().Info(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogInfof(format string, args ...interface{}) {
+	c.logger().Infof(c.format+format, args...)
+}
+
+func (c *requestIDLoggerContext) LogWarn(args ...interface{}) {
+	c.logger().Warn(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogWarnf(format string, args ...interface{}) {
+	c.logger().Warnf(c.format+format, args...)
+}
+
+func (c *requestIDLoggerContext) LogError(args ...interface{}) {
+	c.logger().Error(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogErrorf(format string, args ...interface{}) {
+	c.logger().Errorf(c.format+format, args...)
+}
+
+func (c *requestIDLoggerContext) LogPanic(args ...interface{}) {
+	c.logger().Panic(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogPanicf(format string, args ...interface{}) {
+	c.logger().Panicf(c.format+format, args...)
+}
+
+func (c *requestIDLoggerContext) LogFatal(args ...interface{}) {
+	c.logger().Fatal(c.args(args)...)
+}
+
+func (c *requestIDLoggerContext) LogFatalf(format string, args ...interface{}) {
+	c.logger().Fatalf(c.format+format, args...)
+}
+
+// requestIDLogger 在每行日志前面加上请求 ID 的 StdLogger，作用和
+// SpringLogger.StdLoggerWrapper 相同，调用栈的深度也相同
+type requestIDLogger struct {
+	l SpringLogger.StdLogger
+	c *requestIDLoggerContext
+}
+
+func (w *requestIDLogger) Trace(args ...interface{}) {
+	w.l.Trace(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Tracef(format string, args ...interface{}) {
+	w.l.Tracef(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Debug(args ...interface{}) {
+	w.l.Debug(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Debugf(format string, args ...interface{}) {
+	w.l.Debugf(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Info(args ...interface{}) {
+	w.l.Info(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Infof(format string, args ...interface{}) {
+	w.l.Infof(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Warn(args ...interface{}) {
+	w.l.Warn(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Warnf(format string, args ...interface{}) {
+	w.l.Warnf(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Error(args ...interface{}) {
+	w.l.Error(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Errorf(format string, args ...interface{}) {
+	w.l.Errorf(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Panic(args ...interface{}) {
+	w.l.Panic(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Panicf(format string, args ...interface{}) {
+	w.l.Panicf(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Fatal(args ...interface{}) {
+	w.l.Fatal(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Fatalf(format string, args ...interface{}) {
+	w.l.Fatalf(w.c.format+format, args...)
+}
+
+func (w *requestIDLogger) Print(args ...interface{}) {
+	w.l.Print(w.c.args(args)...)
+}
+
+func (w *requestIDLogger) Printf(format string, args ...interface{}) {
+	w.l.Printf(w.c.format+format, args...)
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-logger"
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+// captureLogger 原样记录收到的 Info 日志的 StdLogger
+type captureLogger struct {
+	SpringLogger.StdLogger
+	lines chan string
+}
+
+func (l *captureLogger) Info(args ...interface{}) {
+	if s := fmt.Sprint(args...); strings.Contains(s, "hello") {
+		l.lines <- s
+	}
+}
+
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+func TestRequestIDFilter(t *testing.T) {
+
+	console := SpringLogger.NewConsole(SpringLogger.InfoLevel)
+	lines := make(chan string, 10)
+	SpringLogger.Logger = func(ctx context.Context, tags ...string) SpringLogger.StdLogger {
+		return &captureLogger{StdLogger: console, lines: lines}
+	}
+	defer func() { SpringLogger.Logger = nil }()
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewRequestIDFilter())
+
+	c.GetMapping("/id", func(ctx SpringWeb.WebContext) {
+		ctx.LogInfo("hello")
+		ctx.LogInfof("hello %d%%", 100)
+		ctx.Logger("tag").Info("hello")
+		ctx.String(http.StatusOK, "%s|%s", SpringWeb.RequestID(ctx), SpringWeb.RequestIDFromContext(ctx.Context()))
+	})
+	c.GetBinding("/bind", func(ctx context.Context, req *struct{}) string {
+		return SpringWeb.RequestIDFromContext(ctx)
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	url := "http://" + c.Address()
+
+	t.Run("incoming", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodGet, url+"/id", "", "X-Request-ID", "abc-123")
+		assert.Equal(t, resp.Header.Get("X-Request-ID"), "abc-123")
+		assert.Equal(t, body, "abc-123|abc-123")
+		assert.Equal(t, <-lines, "request_id=abc-123 hello")
+		assert.Equal(t, <-lines, "request_id=abc-123 hello 100%")
+		assert.Equal(t, <-lines, "request_id=abc-123 hello")
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		doRequest(t, http.MethodGet, url+"/id", "", "X-Request-ID", "a%d")
+		assert.Equal(t, <-lines, "request_id=a%d hello")
+		assert.Equal(t, <-lines, "request_id=a%d hello 100%")
+		assert.Equal(t, <-lines, "request_id=a%d hello")
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		for _, header := range []string{"", strings.Repeat("x", 200), "a b"} {
+			resp, body := doRequest(t, http.MethodGet, url+"/id", "", "X-Request-ID", header)
+			id := resp.Header.Get("X-Request-ID")
+			assert.Equal(t, len(id), 32)
+			assert.Equal(t, body, id+"|"+id)
+			assert.Equal(t, <-lines, "request_id="+id+" hello")
+			assert.Equal(t, <-lines, "request_id="+id+" hello 100%")
+			assert.Equal(t, <-lines, "request_id="+id+" hello")
+		}
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		_, body := doRequest(t, http.MethodGet, url+"/bind", "", "X-Request-ID", "abc-123")
+		assert.Equal(t, body, `{"code":200,"msg":"SUCCESS","data":"abc-123"}`)
+	})
+}
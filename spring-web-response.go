@@ -0,0 +1,157 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// ResponseWriter 能够记录响应状态码和响应长度的 http.ResponseWriter，
+// 状态码在第一次写入响应体 (或者调用 WriteHeaderNow) 时才真正写出。
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+
+	// Status 返回响应的状态码
+	Status() int
+
+	// Size 返回已经写出的响应体的字节数
+	Size() int
+
+	// Written 返回响应头是否已经写出
+	Written() bool
+
+	// WriteHeaderNow 立即写出响应头
+	WriteHeaderNow()
+}
+
+// responseWriter ResponseWriter 的默认实现
+type responseWriter struct {
+	http.ResponseWriter
+
+	status  int
+	size    int
+	written bool
+}
+
+// NewResponseWriter ResponseWriter 的构造函数
+func NewResponseWriter(w http.ResponseWriter) ResponseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// reset 重置 responseWriter 以便复用
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+}
+
+// Status 返回响应的状态码
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// Size 返回已经写出的响应体的字节数
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+// Written 返回响应头是否已经写出
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+// WriteHeader 记录状态码，响应头写出之后的调用将被忽略
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 立即写出响应头
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// Flush 实现 http.Flusher 接口
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.written = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
+}
+
+// headResponseWriter 使用 GET 方法的处理函数响应 HEAD 请求时使用的
+// http.ResponseWriter，丢弃响应体并在结束时计算 Content-Length。
+type headResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	size   int
+}
+
+func newHeadResponseWriter(w http.ResponseWriter) *headResponseWriter {
+	return &headResponseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader 记录状态码，直到 finish 时才真正写出
+func (w *headResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+// Write 丢弃响应体，只记录长度
+func (w *headResponseWriter) Write(data []byte) (int, error) {
+	w.size += len(data)
+	return len(data), nil
+}
+
+// Flush 响应头需要等到 finish 时才能确定，因此什么也不做
+func (w *headResponseWriter) Flush() {}
+
+// finish 设置 Content-Length 并写出响应头
+func (w *headResponseWriter) finish() {
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Length") == "" && w.size > 0 {
+		h.Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
@@ -16,11 +16,14 @@
 
 package SpringWeb
 
-// Router 路由分组
+// Router 路由分组，可以通过 Route 创建子分组，子分组的路由继承所有祖先分组的
+// basePath 和过滤器。
 type Router struct {
 	UrlRegister
 
 	mapping  WebMapping
+	parent   *Router
+	host     string
 	basePath string
 	filters  []Filter
 }
@@ -41,6 +44,54 @@ func routerWithMapping(mapping WebMapping, basePath string, filters []Filter) *R
 }
 
 func (r *Router) request(method uint32, path string, fn Handler, filters []Filter) *Mapper {
-	filters = append(r.filters, filters...)
-	return r.mapping.Request(method, r.basePath+path, fn, filters...)
+
+	// 按照执行阶段和顺序排序，排序返回的是副本，不会和分组共享底层数组
+	n := len(r.filters)
+	filters = SortFilters(append(r.filters[:n:n], filters...))
+
+	if r.parent != nil {
+		return r.parent.request(method, r.basePath+path, fn, filters)
+	}
+
+	if r.host == "" {
+		return r.mapping.Request(method, r.basePath+path, fn, filters...)
+	}
+	m := NewMapper(method, CleanPath(r.basePath+path), fn, filters)
+	m.host = r.host
+	return r.mapping.AddMapper(m)
+}
+
+// Route 创建子分组，子分组的 basePath 和过滤器都追加在当前分组之后
+func (r *Router) Route(subPath string, filters ...Filter) *Router {
+	child := routerWithMapping(r.mapping, subPath, filters)
+	child.parent = r
+	return child
+}
+
+// Use 添加过滤器，只对之后在当前分组及其子分组中注册的路由生效
+func (r *Router) Use(filter ...Filter) *Router {
+	n := len(r.filters)
+	r.filters = append(r.filters[:n:n], filter...)
+	return r
+}
+
+// BasePath 返回包含所有祖先分组的完整 basePath
+func (r *Router) BasePath() string {
+	if r.parent != nil {
+		return r.parent.BasePath() + r.basePath
+	}
+	return r.basePath
+}
+
+// Host 返回路由分组的 Host 路由，为空时匹配任意 Host
+func (r *Router) Host() string {
+	if r.parent != nil {
+		return r.parent.Host()
+	}
+	return r.host
+}
+
+// URLFor 根据 Mapper 的名称生成 URL 地址，生成的地址包含路由分组的 basePath
+func (r *Router) URLFor(name string, params ...interface{}) (string, error) {
+	return r.mapping.URLFor(name, params...)
 }
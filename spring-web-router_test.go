@@ -0,0 +1,57 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestRouter_Route(t *testing.T) {
+
+	a, b, c, d := &stringFilter{"a"}, &stringFilter{"b"}, &stringFilter{"c"}, &stringFilter{"d"}
+
+	m := SpringWeb.NewDefaultWebMapping()
+	api := m.Route("/api", a)
+	v1 := api.Route("/v1", b)
+	users := v1.Route("/users")
+
+	m1 := users.GetMapping("/{id}", emptyHandler, c)
+	assert.Equal(t, m1.Path(), "/api/v1/users/{id}")
+	assert.Equal(t, m1.Filters(), []SpringWeb.Filter{a, b, c})
+	assert.Equal(t, users.BasePath(), "/api/v1/users")
+
+	// Use 只对之后注册的路由生效，并且对子分组同样生效
+	v1.Use(d)
+	m2 := users.GetMapping("/me", emptyHandler)
+	assert.Equal(t, m2.Filters(), []SpringWeb.Filter{a, b, d})
+	assert.Equal(t, m1.Filters(), []SpringWeb.Filter{a, b, c})
+
+	m3 := api.GetMapping("/ping", emptyHandler)
+	assert.Equal(t, m3.Path(), "/api/ping")
+	assert.Equal(t, m3.Filters(), []SpringWeb.Filter{a})
+
+	admin := m.Host("admin.example.com").Route("/admin")
+	m4 := admin.Route("/users").GetMapping("", emptyHandler)
+	assert.Equal(t, m4.Host(), "admin.example.com")
+	assert.Equal(t, m4.Path(), "/admin/users")
+	assert.Equal(t, admin.Host(), "admin.example.com")
+
+	assert.Equal(t, len(m.Mappers()), 4)
+}
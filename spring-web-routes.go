@@ -0,0 +1,160 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo 路由信息
+type RouteInfo struct {
+	Methods []string `json:"methods"`
+	Host    string   `json:"host,omitempty"`
+	Path    string   `json:"path"`
+	Name    string   `json:"name,omitempty"`
+	Handler string   `json:"handler"`
+	File    string   `json:"file"`
+	Line    int      `json:"line"`
+	Filters []string `json:"filters,omitempty"` // 路由级别的过滤器类型
+	Swagger string   `json:"swagger,omitempty"` // Swagger 文档的 operation id
+}
+
+// RouteTable 路由表，按照 Host、路径和方法排序，便于比较不同版本之间的差异
+type RouteTable []RouteInfo
+
+// GetRouteTable 返回路由表的当前内容
+func GetRouteTable(mapping WebMapping) RouteTable {
+
+	var t RouteTable
+	for _, m := range mapping.Mappers() {
+		info := RouteInfo{
+			Methods: GetMethod(m.Method()),
+			Host:    m.Host(),
+			Path:    m.Path(),
+			Name:    m.GetName(),
+		}
+		if m.Handler() != nil {
+			info.File, info.Line, info.Handler = m.Handler().FileLine()
+		}
+		for _, f := range m.Filters() {
+			info.Filters = append(info.Filters, filterName(f))
+		}
+		if op := m.GetSwagger(); op != nil {
+			info.Swagger = op.ID
+		}
+		t = append(t, info)
+	}
+
+	sort.Slice(t, func(i, j int) bool {
+		if t[i].Host != t[j].Host {
+			return t[i].Host < t[j].Host
+		}
+		if t[i].Path != t[j].Path {
+			return t[i].Path < t[j].Path
+		}
+		return strings.Join(t[i].Methods, ",") < strings.Join(t[j].Methods, ",")
+	})
+	return t
+}
+
+// JSON 导出为 JSON 格式
+func (t RouteTable) JSON() ([]byte, error) {
+	if t == nil {
+		t = RouteTable{}
+	}
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// routeTableHeader 表格形式的表头
+var routeTableHeader = []string{"METHODS", "HOST", "PATH", "NAME", "HANDLER", "FILTERS", "SWAGGER"}
+
+// row 返回表格形式的一行
+func (info *RouteInfo) row() []string {
+	handler := info.Handler
+	if info.File != "" {
+		handler = fmt.Sprintf("%s (%s:%d)", info.Handler, info.File, info.Line)
+	}
+	return []string{
+		strings.Join(info.Methods, ","),
+		info.Host,
+		info.Path,
+		info.Name,
+		handler,
+		strings.Join(info.Filters, ","),
+		info.Swagger,
+	}
+}
+
+// Markdown 导出为 Markdown 表格
+func (t RouteTable) Markdown() string {
+	var buf bytes.Buffer
+	writeRow := func(cells []string) {
+		buf.WriteString("|")
+		for _, cell := range cells {
+			buf.WriteString(" " + strings.Replace(cell, "|", "\\|", -1) + " |")
+		}
+		buf.WriteString("\n")
+	}
+	writeRow(routeTableHeader)
+	sep := make([]string, len(routeTableHeader))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeRow(sep)
+	for i := range t {
+		writeRow(t[i].row())
+	}
+	return buf.String()
+}
+
+// Text 导出为对齐的纯文本表格
+func (t RouteTable) Text() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(routeTableHeader, "\t"))
+	for i := range t {
+		fmt.Fprintln(w, strings.Join(t[i].row(), "\t"))
+	}
+	_ = w.Flush()
+	return buf.String()
+}
+
+// RouteTableHandler 返回输出路由表的处理函数，可以注册为管理接口，并且应当
+// 配置必要的访问控制。通过 format 查询参数选择 json (默认)、markdown 或者 text 格式。
+func RouteTableHandler(mapping WebMapping) HandlerFunc {
+	return func(ctx WebContext) {
+		t := GetRouteTable(mapping)
+		switch ctx.QueryParam("format") {
+		case "markdown":
+			ctx.Blob(http.StatusOK, "text/markdown; charset=UTF-8", []byte(t.Markdown()))
+		case "text":
+			ctx.String(http.StatusOK, "%s", t.Text())
+		default:
+			b, err := t.JSON()
+			if err != nil {
+				panic(err)
+			}
+			ctx.JSONBlob(http.StatusOK, b)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestGetRouteTable(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.PostMapping("/users", emptyHandler, &stringFilter{"a"},
+		SpringWeb.NewConditionalFilter(SpringWeb.WithOrder(&stringFilter{"b"}, SpringWeb.PostRoutingPhase, 0)).Include("/users"),
+	).Swagger("createUser")
+	m.GetMapping("/users/{id}", emptyHandler).Name("user")
+	m.Host("api.example.com").GetMapping("/users", emptyHandler)
+
+	table := SpringWeb.GetRouteTable(m)
+	assert.Equal(t, len(table), 3)
+	assert.Equal(t, table[0].Path, "/users")
+	assert.Equal(t, table[0].Methods, []string{"POST"})
+	assert.Equal(t, table[0].Filters, []string{"*SpringWeb_test.stringFilter", "*SpringWeb_test.stringFilter"})
+	assert.Equal(t, table[0].Swagger, "createUser")
+	assert.Equal(t, table[0].Handler, "emptyHandler")
+	assert.Equal(t, strings.HasSuffix(table[0].File, "spring-web-mapping_test.go"), true)
+	assert.Equal(t, table[1].Name, "user")
+	assert.Equal(t, table[2].Host, "api.example.com")
+
+	t.Run("json", func(t *testing.T) {
+		b, err := table.JSON()
+		assert.Equal(t, err, nil)
+		var r SpringWeb.RouteTable
+		assert.Equal(t, json.Unmarshal(b, &r), nil)
+		assert.Equal(t, r, table)
+		b, _ = SpringWeb.RouteTable(nil).JSON()
+		assert.Equal(t, string(b), "[]")
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(table.Markdown()), "\n")
+		assert.Equal(t, len(lines), 5)
+		assert.Equal(t, lines[0], "| METHODS | HOST | PATH | NAME | HANDLER | FILTERS | SWAGGER |")
+		assert.Equal(t, lines[1], "| --- | --- | --- | --- | --- | --- | --- |")
+		assert.Equal(t, strings.HasPrefix(lines[3], "| GET |  | /users/{id} | user | emptyHandler ("), true)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(table.Text()), "\n")
+		assert.Equal(t, len(lines), 4)
+		assert.Equal(t, strings.HasPrefix(lines[0], "METHODS  HOST"), true)
+		assert.Equal(t, strings.Contains(lines[3], "api.example.com  /users"), true)
+	})
+}
@@ -98,6 +98,13 @@ func defaultRpcInvoke(webCtx WebContext, fn func(WebContext) interface{}) {
 
 	defer func() {
 		if r := recover(); r != nil {
+
+			// 请求体超过限制时返回 413，而不是 RpcResult
+			if r == ErrBodyTooLarge || bodyTooLarge(webCtx.Request()) {
+				tooLarge(webCtx)
+				return
+			}
+
 			result, ok := r.(*SpringError.RpcResult)
 			if !ok {
 				var err error
@@ -98,9 +98,9 @@ func (s *WebServer) Start() {
 			c.SetRecoveryFilter(s.recoveryFilter)
 		}
 
-		// 添加 Server 的普通过滤器给 Container
-		filters := append(s.filters, c.GetFilters()...)
-		c.ResetFilters(filters)
+		// 添加 Server 的普通过滤器给 Container，并按照执行阶段和顺序排序
+		filters := append(append([]Filter(nil), s.filters...), c.GetFilters()...)
+		c.ResetFilters(SortFilters(filters))
 
 		c.Start()
 	}
@@ -152,8 +152,11 @@ func (s *Swagger) WithTags(tags ...spec.Tag) *Swagger {
 func (s *Swagger) AddPath(path string, method uint32, op *Operation,
 	parameters ...spec.Parameter) *Swagger {
 
-	path = strings.TrimPrefix(path, s.BasePath)
-	path = strings.TrimRight(path, "/")
+	// 和路由使用相同的规范化路径，末尾的 / 是否有意义由路由策略决定
+	path = swaggerPath(strings.TrimPrefix(CleanPath(path), s.BasePath))
+	if path == "" {
+		path = "/"
+	}
 	pathItem, ok := s.Paths.Paths[path]
 
 	if !ok {
@@ -187,6 +190,29 @@ func (s *Swagger) AddPath(path string, method uint32, op *Operation,
 	return s
 }
 
+// swaggerPath 将路由地址转换成 swagger 使用的 {} 风格，并去掉命名参数的约束条件
+func swaggerPath(path string) string {
+	if path == "" {
+		return path
+	}
+	var sb strings.Builder
+	for _, seg := range parsePath(path) {
+		switch seg.kind {
+		case namedSegment:
+			sb.WriteString("/{" + seg.name + "}")
+		case wildCardSegment:
+			if seg.name != "" {
+				sb.WriteString("/{*:" + seg.name + "}")
+			} else {
+				sb.WriteString("/{*}")
+			}
+		default:
+			sb.WriteString("/" + seg.name)
+		}
+	}
+	return sb.String()
+}
+
 // AddDefinition 添加一个定义
 func (s *Swagger) AddDefinition(name string, schema *spec.Schema) *Swagger {
 	s.Definitions[name] = *schema
@@ -500,6 +526,16 @@ func (o *Operation) SecuredWith(name string, scopes ...string) *Operation {
 	return o
 }
 
+// hasSecurity 返回 operation 是否已经包含该认证方式
+func (o *Operation) hasSecurity(name string) bool {
+	for _, m := range o.Security {
+		if _, ok := m[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // WithDefaultResponse adds a default response to the operation.
 func (o *Operation) WithDefaultResponse(response *spec.Response) *Operation {
 	o.Operation.WithDefaultResponse(response)
@@ -518,9 +554,9 @@ func (o *Operation) BindParam(i interface{}, description string) *Operation {
 	return o
 }
 
-// parseBind 解析绑定的请求参数
+// parseBind 解析绑定的请求参数，重复调用不会重复添加 body 参数
 func (o *Operation) parseBind() error {
-	if o.bindParam != nil && o.bindParam.param != nil {
+	if o.bindParam != nil && o.bindParam.param != nil && !o.hasParam("body", "body") {
 		t := reflect.TypeOf(o.bindParam.param)
 		if t.Kind() == reflect.Ptr {
 			t = t.Elem()
@@ -536,6 +572,32 @@ func (o *Operation) parseBind() error {
 	return nil
 }
 
+// parsePathParams 为有约束条件的命名参数生成对应类型的 path 参数，已经存在
+// 的同名参数不会被覆盖
+func (o *Operation) parsePathParams(path string) {
+	for _, seg := range parsePath(path) {
+		if seg.kind != namedSegment || seg.constraint == "" || o.hasParam(seg.name, "path") {
+			continue
+		}
+		c := getConstraint(seg.constraint)
+		param := PathParam(seg.name, c.typ, c.format)
+		if c.pattern != "" {
+			param.WithPattern(c.pattern)
+		}
+		o.AddParam(param)
+	}
+}
+
+// hasParam 是否存在指定名称和位置的参数
+func (o *Operation) hasParam(name, in string) bool {
+	for _, p := range o.Parameters {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
 // HeaderParam creates a header parameter, this is always required by default
 func HeaderParam(name string, typ, format string) *spec.Parameter {
 	param := spec.HeaderParam(name)
@@ -590,3 +652,21 @@ func NewBindResponse(i interface{}, description string) *spec.Response {
 
 	return resp
 }
+
+// SwaggerAware 需要向 Swagger 文档注册信息的过滤器，例如认证过滤器注册认证方式。
+// 容器生成 Swagger 文档时对容器级别和路由级别的过滤器调用该接口。
+type SwaggerAware interface {
+	RegisterSwagger(s *Swagger)
+}
+
+// registerSwaggerFilters 对实现了 SwaggerAware 的过滤器调用 RegisterSwagger
+func registerSwaggerFilters(s *Swagger, filters []Filter) {
+	for _, f := range filters {
+		for _, v := range unwrapFilter(f) {
+			if a, ok := v.(SwaggerAware); ok {
+				a.RegisterSwagger(s)
+				break
+			}
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/go-spring/go-spring-test"
 	"github.com/go-spring/go-spring-utils"
 	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
 )
 
 func TestSwagger(t *testing.T) {
@@ -877,3 +878,28 @@ func TestSwagger(t *testing.T) {
 		SpringTest.DiffMap(t, m1, m2)
 	})
 }
+
+func TestSwagger_PathConstraint(t *testing.T) {
+
+	c := SpringWeb.NewBaseWebContainer(SpringWeb.ContainerConfig{})
+	c.Swagger().WithBasePath("/v2")
+
+	c.GetMapping("/v2/order/{id:int}/item/{code:[A-Z]{3}}", func(ctx SpringWeb.WebContext) {}).
+		Swagger("getItem").
+		AddParam(SpringWeb.PathParam("code", "string", "").WithDescription("item code"))
+
+	c.PreStart()
+
+	item, ok := c.Swagger().Paths.Paths["/order/{id}/item/{code}"]
+	assert.Equal(t, ok, true)
+
+	params := item.Get.Parameters
+	assert.Equal(t, len(params), 2)
+	assert.Equal(t, params[0].Name, "code")
+	assert.Equal(t, params[0].Description, "item code")
+	assert.Equal(t, params[0].Pattern, "")
+	assert.Equal(t, params[1].Name, "id")
+	assert.Equal(t, params[1].In, "path")
+	assert.Equal(t, params[1].Type, "integer")
+	assert.Equal(t, params[1].Format, "int64")
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChainTraceKey WebContext 中保存过滤器链条耗时记录的 Key
+const ChainTraceKey = "@ChainTrace"
+
+// TraceFilterOrder TraceFilter 的顺序值，在日志过滤器之前执行
+const TraceFilterOrder = -3000
+
+// FilterSpan 过滤器或者处理函数的一次执行记录
+type FilterSpan struct {
+	Name  string        // 过滤器的类型或者处理函数的名称
+	Depth int           // 嵌套深度，路由匹配之后的过滤器链条比路由匹配之前的深一层
+	Start time.Time     // 进入时间
+	End   time.Time     // 退出时间
+	Self  time.Duration // 自身耗时，不包括同一链条中后续过滤器和处理函数的耗时
+}
+
+// Total 返回总耗时
+func (s *FilterSpan) Total() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// ChainTrace 一次请求中所有过滤器和处理函数的执行记录，按照进入的顺序排列
+type ChainTrace struct {
+	Spans []FilterSpan
+	depth int
+}
+
+// GetChainTrace 返回当前请求的执行记录，没有启用 TraceFilter 时返回 nil
+func GetChainTrace(ctx WebContext) *ChainTrace {
+	trace, _ := ctx.Get(ChainTraceKey).(*ChainTrace)
+	return trace
+}
+
+// String 返回便于打印的执行记录，每个过滤器占一行
+func (t *ChainTrace) String() string {
+	var sb strings.Builder
+	for i := range t.Spans {
+		s := &t.Spans[i]
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s%s self: %v total: %v", strings.Repeat("  ", s.Depth), s.Name, s.Self, s.Total())
+	}
+	return sb.String()
+}
+
+// TraceReporter 输出请求的执行记录，例如打印日志或者转换成链路追踪系统的 span
+type TraceReporter interface {
+	Report(ctx WebContext, trace *ChainTrace)
+}
+
+// TraceReporterFunc 函数形式的 TraceReporter
+type TraceReporterFunc func(ctx WebContext, trace *ChainTrace)
+
+func (f TraceReporterFunc) Report(ctx WebContext, trace *ChainTrace) {
+	f(ctx, trace)
+}
+
+// LogTraceReporter 使用请求的日志接口打印执行记录，Threshold 不为 0 时只打印
+// 总耗时超过 Threshold 的请求
+type LogTraceReporter struct {
+	Threshold time.Duration
+}
+
+func (r *LogTraceReporter) Report(ctx WebContext, trace *ChainTrace) {
+	if len(trace.Spans) == 0 {
+		return
+	}
+	if r.Threshold > 0 && trace.Spans[0].Total() < r.Threshold {
+		return
+	}
+	ctx.LogInfo("filter chain trace:\n", trace)
+}
+
+// TraceFilter 记录每个过滤器和处理函数的进入、退出时间，结束之后交给 reporter
+// 输出，执行记录同时保存在 WebContext 中，可以通过 GetChainTrace 获取。该过滤器
+// 是可选的，没有添加时 InvokeHandler 只多一次 WebContext.Get 的开销。
+type TraceFilter struct {
+	reporter TraceReporter
+}
+
+// NewTraceFilter TraceFilter 的构造函数，reporter 为 nil 时只记录不输出
+func NewTraceFilter(reporter TraceReporter) *TraceFilter {
+	return &TraceFilter{reporter: reporter}
+}
+
+// Phase TraceFilter 在路由匹配之前执行
+func (f *TraceFilter) Phase() FilterPhase {
+	return PreRoutingPhase
+}
+
+// Order TraceFilter 在其他过滤器之前执行
+func (f *TraceFilter) Order() int {
+	return TraceFilterOrder
+}
+
+func (f *TraceFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	trace := &ChainTrace{}
+	ctx.Set(ChainTraceKey, trace)
+
+	// 使用记录耗时的链条执行剩下的过滤器
+	if c, ok := chain.(*DefaultFilterChain); ok {
+		newTracedFilterChain(c.filters[c.next:], trace).Next(ctx)
+	} else {
+		chain.Next(ctx)
+	}
+
+	if f.reporter != nil {
+		f.reporter.Report(ctx, trace)
+	}
+}
+
+// tracedFilterChain 记录每个过滤器耗时的过滤器链条
+type tracedFilterChain struct {
+	DefaultFilterChain
+	trace *ChainTrace
+	depth int
+	inner time.Duration // 最近一次 Next 的总耗时
+}
+
+func newTracedFilterChain(filters []Filter, trace *ChainTrace) *tracedFilterChain {
+	return &tracedFilterChain{
+		DefaultFilterChain: DefaultFilterChain{filters: filters},
+		trace:              trace,
+		depth:              trace.depth,
+	}
+}
+
+func (chain *tracedFilterChain) Next(ctx WebContext) {
+
+	// 链条执行到此结束
+	if chain.next >= len(chain.filters) {
+		return
+	}
+
+	f := chain.filters[chain.next]
+	chain.next++
+
+	i := len(chain.trace.Spans)
+	chain.trace.Spans = append(chain.trace.Spans, FilterSpan{
+		Name:  filterName(f),
+		Depth: chain.depth,
+		Start: time.Now(),
+	})
+
+	// 嵌套的链条 (例如路由匹配之后的过滤器) 深一层
+	depth := chain.trace.depth
+	chain.trace.depth = chain.depth + 1
+	chain.inner = 0
+	f.Invoke(ctx, chain)
+	chain.trace.depth = depth
+
+	s := &chain.trace.Spans[i]
+	s.End = time.Now()
+	s.Self = s.Total() - chain.inner
+	chain.inner = s.Total()
+}
+
+// filterName 返回过滤器的名称，包装过的过滤器返回被包装的过滤器的类型
+func filterName(f Filter) string {
+	filters := unwrapFilter(f)
+	f = filters[len(filters)-1]
+	if n, ok := f.(interface{ name() string }); ok {
+		return n.name()
+	}
+	return fmt.Sprintf("%T", f)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+// sleepFilter 休眠一段时间之后继续执行
+type sleepFilter struct {
+	d time.Duration
+}
+
+func (f *sleepFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.FilterChain) {
+	time.Sleep(f.d)
+	chain.Next(ctx)
+}
+
+// wrapFilter 自定义的包装过滤器
+type wrapFilter struct {
+	f SpringWeb.Filter
+}
+
+func (w *wrapFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.FilterChain) {
+	w.f.Invoke(ctx, chain)
+}
+
+func (w *wrapFilter) Unwrap() SpringWeb.Filter {
+	return w.f
+}
+
+func TestTraceFilter(t *testing.T) {
+
+	spans := make(chan []SpringWeb.FilterSpan, 1)
+	reporter := SpringWeb.TraceReporterFunc(func(ctx SpringWeb.WebContext, trace *SpringWeb.ChainTrace) {
+		spans <- trace.Spans
+	})
+
+	c := SpringWeb.NewHttpContainer(SpringWeb.ContainerConfig{IP: "127.0.0.1"})
+	c.AddFilter(SpringWeb.NewTraceFilter(reporter), &wrapFilter{&sleepFilter{20 * time.Millisecond}})
+	c.GetMapping("/slow", func(ctx SpringWeb.WebContext) {
+		time.Sleep(10 * time.Millisecond)
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	c.Start()
+	defer c.Stop(context.Background())
+
+	resp, _ := doRequest(t, http.MethodGet, "http://"+c.Address()+"/slow", "")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	s := <-spans
+	var names []string
+	var depths []int
+	for _, span := range s {
+		names = append(names, span.Name)
+		depths = append(depths, span.Depth)
+		assert.Equal(t, span.Self <= span.Total(), true)
+	}
+
+	// 日志过滤器、恢复过滤器、路由匹配，然后是路由匹配之后的过滤器和处理函数
+	assert.Equal(t, names[:2], []string{"*SpringWeb.loggerFilter", "*SpringWeb.RecoveryFilter"})
+	assert.Equal(t, names[3], "*SpringWeb_test.sleepFilter")
+	assert.Equal(t, depths, []int{0, 0, 0, 1, 1})
+
+	sleep, handler := s[3], s[4]
+	assert.Equal(t, sleep.Self >= 20*time.Millisecond, true)
+	assert.Equal(t, sleep.Self < sleep.Total(), true)
+	assert.Equal(t, handler.Total() >= 10*time.Millisecond, true)
+	assert.Equal(t, s[0].Total() >= sleep.Total(), true)
+}
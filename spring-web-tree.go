@@ -0,0 +1,320 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"strings"
+)
+
+// RouteParams 路由匹配得到的路径参数，通配符的名称总是 *，其别名保存在
+// WildCardName 字段。调用方预先分配好 Values 的容量可以避免匹配时的内存分配。
+type RouteParams struct {
+	Names        []string
+	Values       []string
+	WildCardName string
+}
+
+// Get 返回路径参数的值，支持使用通配符的别名获取通配符的值
+func (p *RouteParams) Get(name string) string {
+	if name != "" && name == p.WildCardName {
+		name = "*"
+	}
+	for i, n := range p.Names {
+		if n == name {
+			return p.Values[i]
+		}
+	}
+	return ""
+}
+
+// Reset 清空路径参数，保留已经分配的内存
+func (p *RouteParams) Reset() {
+	p.Names = nil
+	p.Values = p.Values[:0]
+	p.WildCardName = ""
+}
+
+// RouteTree 基于 radix tree 的路由匹配器，直接使用 go-spring 的路由语法，匹配
+// 规则和底层的 Web 框架无关：静态路径优先于命名参数，有约束条件的命名参数优先于
+// 没有约束条件的命名参数，命名参数优先于通配符，高优先级的分支匹配失败 (包括约束
+// 条件和 HTTP 方法不匹配) 时回溯尝试低优先级的分支。
+type RouteTree struct {
+	root      routeNode
+	maxParams int
+}
+
+// NewRouteTree RouteTree 的构造函数
+func NewRouteTree() *RouteTree {
+	return &RouteTree{}
+}
+
+// MaxParams 返回所有路由中路径参数的最大数量
+func (t *RouteTree) MaxParams() int {
+	return t.maxParams
+}
+
+// Add 添加一个路由
+func (t *RouteTree) Add(m *Mapper) {
+
+	var (
+		static strings.Builder
+		names  []string
+	)
+
+	entry := &routeEntry{mapper: m}
+	n := &t.root
+
+	for _, seg := range parsePath(m.Path()) {
+		static.WriteString("/")
+		switch seg.kind {
+		case knownSegment:
+			static.WriteString(seg.name)
+		case namedSegment:
+			n = n.addStatic(static.String())
+			static.Reset()
+			n = n.addParam(seg.constraint)
+			names = append(names, seg.name)
+		case wildCardSegment:
+			n = n.addStatic(static.String())
+			static.Reset()
+			n = n.addWildCard()
+			names = append(names, "*")
+			entry.wildCardName = seg.name
+		}
+	}
+
+	n = n.addStatic(static.String())
+	entry.names = names
+	n.routes = append(n.routes, entry)
+
+	if len(names) > t.maxParams {
+		t.maxParams = len(names)
+	}
+}
+
+// Find 查找和 method、path 匹配的路由，并将路径参数保存到 params 中
+func (t *RouteTree) Find(method string, path string, params *RouteParams) *Mapper {
+	values := params.Values[:0]
+	entry, values := t.root.find(path, methodBit(method), values)
+	if entry == nil {
+		params.Reset()
+		return nil
+	}
+	params.Names = entry.names
+	params.Values = values
+	params.WildCardName = entry.wildCardName
+	return entry.mapper
+}
+
+// Allowed 返回和 path 匹配的所有路由的 HTTP 方法掩码，用于生成 Allow 响应头
+func (t *RouteTree) Allowed(path string) uint32 {
+	return t.root.allowed(path)
+}
+
+// routeEntry 在某个节点结束的路由
+type routeEntry struct {
+	mapper       *Mapper
+	names        []string
+	wildCardName string
+}
+
+const (
+	staticNode = iota
+	paramNode
+	wildCardNode
+)
+
+// routeNode radix tree 的节点
+type routeNode struct {
+	kind       int
+	prefix     string           // 静态节点的路径前缀
+	constraint *paramConstraint // 命名参数节点的约束条件
+	statics    []*routeNode
+	params     []*routeNode // 有约束条件的命名参数节点，按照注册顺序匹配
+	param      *routeNode   // 没有约束条件的命名参数节点
+	wildCard   *routeNode
+	routes     []*routeEntry
+}
+
+// addStatic 添加静态路径，返回路径结束位置的节点
+func (n *routeNode) addStatic(s string) *routeNode {
+
+	if s == "" {
+		return n
+	}
+
+	for _, child := range n.statics {
+		if child.prefix[0] != s[0] {
+			continue
+		}
+
+		i := commonPrefix(child.prefix, s)
+
+		// 拆分节点，保持 child 指针不变以免修改父节点
+		if i < len(child.prefix) {
+			tail := *child
+			tail.prefix = child.prefix[i:]
+			*child = routeNode{
+				kind:    staticNode,
+				prefix:  child.prefix[:i],
+				statics: []*routeNode{&tail},
+			}
+		}
+
+		return child.addStatic(s[i:])
+	}
+
+	child := &routeNode{kind: staticNode, prefix: s}
+	n.statics = append(n.statics, child)
+	return child
+}
+
+// addParam 添加命名参数节点
+func (n *routeNode) addParam(constraint string) *routeNode {
+
+	if constraint == "" {
+		if n.param == nil {
+			n.param = &routeNode{kind: paramNode}
+		}
+		return n.param
+	}
+
+	for _, child := range n.params {
+		if child.constraint.expr == constraint {
+			return child
+		}
+	}
+
+	child := &routeNode{kind: paramNode, constraint: getConstraint(constraint)}
+	n.params = append(n.params, child)
+	return child
+}
+
+// addWildCard 添加通配符节点
+func (n *routeNode) addWildCard() *routeNode {
+	if n.wildCard == nil {
+		n.wildCard = &routeNode{kind: wildCardNode}
+	}
+	return n.wildCard
+}
+
+// find 在 n 的子树中匹配剩余的路径 path，n 本身已经匹配成功
+func (n *routeNode) find(path string, bit uint32, values []string) (*routeEntry, []string) {
+
+	if path == "" {
+		if entry := n.match(bit); entry != nil {
+			return entry, values
+		}
+	}
+
+	// 静态路径优先
+	if path != "" {
+		for _, child := range n.statics {
+			if strings.HasPrefix(path, child.prefix) {
+				if entry, v := child.find(path[len(child.prefix):], bit, values); entry != nil {
+					return entry, v
+				}
+			}
+		}
+	}
+
+	// 命名参数匹配到下一个 / 为止，且不能为空
+	if (n.param != nil || len(n.params) > 0) && path != "" && path[0] != '/' {
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		for _, child := range n.params {
+			if child.constraint.match(path[:i]) {
+				if entry, v := child.find(path[i:], bit, append(values, path[:i])); entry != nil {
+					return entry, v
+				}
+			}
+		}
+		if n.param != nil {
+			if entry, v := n.param.find(path[i:], bit, append(values, path[:i])); entry != nil {
+				return entry, v
+			}
+		}
+	}
+
+	// 通配符匹配剩余的全部路径
+	if n.wildCard != nil {
+		if entry := n.wildCard.match(bit); entry != nil {
+			return entry, append(values, path)
+		}
+	}
+
+	return nil, values
+}
+
+// allowed 返回 n 的子树中和剩余路径 path 匹配的所有路由的 HTTP 方法掩码
+func (n *routeNode) allowed(path string) uint32 {
+	var method uint32
+
+	if path == "" {
+		for _, entry := range n.routes {
+			method |= entry.mapper.Method()
+		}
+	} else {
+		for _, child := range n.statics {
+			if strings.HasPrefix(path, child.prefix) {
+				method |= child.allowed(path[len(child.prefix):])
+			}
+		}
+		if path[0] != '/' {
+			i := strings.IndexByte(path, '/')
+			if i < 0 {
+				i = len(path)
+			}
+			for _, child := range n.params {
+				if child.constraint.match(path[:i]) {
+					method |= child.allowed(path[i:])
+				}
+			}
+			if n.param != nil {
+				method |= n.param.allowed(path[i:])
+			}
+		}
+	}
+
+	if n.wildCard != nil {
+		for _, entry := range n.wildCard.routes {
+			method |= entry.mapper.Method()
+		}
+	}
+	return method
+}
+
+// match 返回在此节点结束并且支持 HTTP 方法的路由
+func (n *routeNode) match(bit uint32) *routeEntry {
+	for _, entry := range n.routes {
+		if entry.mapper.Method()&bit != 0 {
+			return entry
+		}
+	}
+	return nil
+}
+
+// commonPrefix 返回两个字符串公共前缀的长度
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
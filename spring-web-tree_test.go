@@ -0,0 +1,150 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-spring/go-spring-web"
+	"github.com/magiconair/properties/assert"
+)
+
+func newTestTree(paths map[string]uint32) (*SpringWeb.RouteTree, map[string]*SpringWeb.Mapper) {
+	tree := SpringWeb.NewRouteTree()
+	mappers := make(map[string]*SpringWeb.Mapper)
+	for path, method := range paths {
+		m := SpringWeb.NewMapper(method, path, nil, nil)
+		mappers[path] = m
+		tree.Add(m)
+	}
+	return tree, mappers
+}
+
+func TestRouteTree_Find(t *testing.T) {
+
+	tree, mappers := newTestTree(map[string]uint32{
+		"/":                      SpringWeb.MethodGet,
+		"/users":                 SpringWeb.MethodGetPost,
+		"/users/me":              SpringWeb.MethodGet,
+		"/users/{id}":            SpringWeb.MethodAny,
+		"/users/:id/books/:book": SpringWeb.MethodGet,
+		"/user":                  SpringWeb.MethodGet,
+		"/static/*":              SpringWeb.MethodGet,
+		"/files/{*:path}":        SpringWeb.MethodGet,
+		"/files/readme":          SpringWeb.MethodGet,
+	})
+
+	params := &SpringWeb.RouteParams{}
+
+	find := func(method, path string) *SpringWeb.Mapper {
+		return tree.Find(method, path, params)
+	}
+
+	t.Run("static", func(t *testing.T) {
+		assert.Equal(t, find(http.MethodGet, "/"), mappers["/"])
+		assert.Equal(t, find(http.MethodGet, "/users"), mappers["/users"])
+		assert.Equal(t, find(http.MethodPost, "/users"), mappers["/users"])
+		assert.Equal(t, find(http.MethodGet, "/user"), mappers["/user"])
+		assert.Equal(t, find(http.MethodGet, "/users/me"), mappers["/users/me"])
+		assert.Equal(t, len(params.Names), 0)
+	})
+
+	t.Run("param", func(t *testing.T) {
+		assert.Equal(t, find(http.MethodGet, "/users/42"), mappers["/users/{id}"])
+		assert.Equal(t, params.Get("id"), "42")
+		assert.Equal(t, find(http.MethodGet, "/users/42/books/go"), mappers["/users/:id/books/:book"])
+		assert.Equal(t, params.Names, []string{"id", "book"})
+		assert.Equal(t, params.Values, []string{"42", "go"})
+		assert.Equal(t, find(http.MethodGet, "/users/"), (*SpringWeb.Mapper)(nil))
+	})
+
+	t.Run("backtrack", func(t *testing.T) {
+		// /users/me 只注册了 GET 方法，DELETE 回溯到 /users/{id}
+		assert.Equal(t, find(http.MethodDelete, "/users/me"), mappers["/users/{id}"])
+		assert.Equal(t, params.Get("id"), "me")
+		assert.Equal(t, find(http.MethodGet, "/files/readme"), mappers["/files/readme"])
+		assert.Equal(t, find(http.MethodGet, "/files/readme.md"), mappers["/files/{*:path}"])
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		assert.Equal(t, find(http.MethodGet, "/static/js/a.js"), mappers["/static/*"])
+		assert.Equal(t, params.Get("*"), "js/a.js")
+		assert.Equal(t, find(http.MethodGet, "/files/a/b"), mappers["/files/{*:path}"])
+		assert.Equal(t, params.Get("path"), "a/b")
+		assert.Equal(t, params.Get("*"), "a/b")
+		assert.Equal(t, find(http.MethodGet, "/static/"), mappers["/static/*"])
+		assert.Equal(t, params.Get("*"), "")
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		assert.Equal(t, find(http.MethodPut, "/users"), (*SpringWeb.Mapper)(nil))
+		assert.Equal(t, find(http.MethodGet, "/none"), (*SpringWeb.Mapper)(nil))
+		assert.Equal(t, find("UNKNOWN", "/"), (*SpringWeb.Mapper)(nil))
+		assert.Equal(t, len(params.Values), 0)
+	})
+
+	t.Run("allocs", func(t *testing.T) {
+		params := &SpringWeb.RouteParams{Values: make([]string, 0, tree.MaxParams())}
+		allocs := testing.AllocsPerRun(100, func() {
+			tree.Find(http.MethodGet, "/users/42/books/go", params)
+			tree.Find(http.MethodGet, "/files/a/b", params)
+			tree.Find(http.MethodGet, "/none", params)
+		})
+		assert.Equal(t, allocs, float64(0))
+	})
+}
+
+func TestRouteTree_Constraint(t *testing.T) {
+
+	tree, mappers := newTestTree(map[string]uint32{
+		"/order/{id:int}":        SpringWeb.MethodGet,
+		"/order/{id:uuid}":       SpringWeb.MethodGet,
+		"/order/{slug:[a-z-]+}":  SpringWeb.MethodGet,
+		"/order/{name}/items":    SpringWeb.MethodGet,
+		"/order/{code:[A-Z]{3}}": SpringWeb.MethodPost,
+	})
+
+	params := &SpringWeb.RouteParams{}
+
+	find := func(method, path string) *SpringWeb.Mapper {
+		return tree.Find(method, path, params)
+	}
+
+	assert.Equal(t, find(http.MethodGet, "/order/123"), mappers["/order/{id:int}"])
+	assert.Equal(t, params.Get("id"), "123")
+	assert.Equal(t, find(http.MethodGet, "/order/2f1c6b8a-9d1e-4c55-8f0e-6a1b2c3d4e5f"), mappers["/order/{id:uuid}"])
+	assert.Equal(t, find(http.MethodGet, "/order/hello-world"), mappers["/order/{slug:[a-z-]+}"])
+	assert.Equal(t, params.Get("slug"), "hello-world")
+	assert.Equal(t, find(http.MethodGet, "/order/ABC"), (*SpringWeb.Mapper)(nil))
+	assert.Equal(t, find(http.MethodPost, "/order/ABC"), mappers["/order/{code:[A-Z]{3}}"])
+	assert.Equal(t, find(http.MethodGet, "/order/Abc1/items"), mappers["/order/{name}/items"])
+	assert.Equal(t, params.Get("name"), "Abc1")
+}
+
+func BenchmarkRouteTree_Find(b *testing.B) {
+	tree, _ := newTestTree(map[string]uint32{
+		"/users/{id}":            SpringWeb.MethodGet,
+		"/users/:id/books/:book": SpringWeb.MethodGet,
+		"/static/*":              SpringWeb.MethodGet,
+	})
+	params := &SpringWeb.RouteParams{Values: make([]string, 0, tree.MaxParams())}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tree.Find(http.MethodGet, "/users/42/books/go", params)
+	}
+}
@@ -18,7 +18,11 @@ package SpringWeb
 
 import (
 	"errors"
+	"fmt"
+	"path"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 // 路由风格有 echo、gin 和 {} 三种，
@@ -27,6 +31,12 @@ import (
 // /a/{b}/c/{e:*} 这种是 {} 风格；
 // /a/{b}/c/{*:e} 这也是 {} 风格;
 // /a/{b}/c/{*} 这种也是 {} 风格。
+//
+// {} 风格的命名参数还可以指定约束条件，不满足约束条件的路径段不会被匹配：
+// /order/{id:int} 只匹配整数；
+// /order/{id:uuid} 只匹配 UUID；
+// /order/{slug:[a-z-]+} 只匹配满足正则表达式的路径段 (正则表达式中不能有 /)。
+// 转换成 echo 和 gin 风格时约束条件会被去掉，转换成 {} 风格时保留约束条件。
 
 type PathStyleEnum int
 
@@ -42,7 +52,7 @@ const DefaultWildCardName = "@_@"
 // pathStyle URL 地址风格
 type pathStyle interface {
 	addKnownPath(path string)
-	addNamedPath(path string)
+	addNamedPath(path string, constraint string)
 	addWildCard(name string)
 	wildCardName() string
 	String() string
@@ -70,7 +80,7 @@ func (p *echoPathStyle) addKnownPath(path string) {
 	p.s.WriteString("/" + path)
 }
 
-func (p *echoPathStyle) addNamedPath(path string) {
+func (p *echoPathStyle) addNamedPath(path string, _ string) {
 	p.s.WriteString("/:" + path)
 }
 
@@ -88,7 +98,7 @@ func (p *ginPathStyle) addKnownPath(path string) {
 	p.s.WriteString("/" + path)
 }
 
-func (p *ginPathStyle) addNamedPath(path string) {
+func (p *ginPathStyle) addNamedPath(path string, _ string) {
 	p.s.WriteString("/:" + path)
 }
 
@@ -109,8 +119,12 @@ func (p *javaPathStyle) addKnownPath(path string) {
 	p.s.WriteString("/" + path)
 }
 
-func (p *javaPathStyle) addNamedPath(path string) {
-	p.s.WriteString("/{" + path + "}")
+func (p *javaPathStyle) addNamedPath(path string, constraint string) {
+	if constraint != "" {
+		p.s.WriteString("/{" + path + ":" + constraint + "}")
+	} else {
+		p.s.WriteString("/{" + path + "}")
+	}
 }
 
 func (p *javaPathStyle) addWildCard(name string) {
@@ -122,6 +136,37 @@ func (p *javaPathStyle) addWildCard(name string) {
 	p.w = name
 }
 
+// CleanPath 返回规范化的路径：以 / 开始，合并连续的 /，处理 . 和 .. 路径段，
+// 但是保留末尾的 /，因为末尾的 / 是否有意义由路由策略决定。
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if np == "/" || p[len(p)-1] != '/' {
+		return np
+	}
+	// 已经是规范化的路径时避免内存分配
+	if len(p) == len(np)+1 && p[:len(np)] == np {
+		return p
+	}
+	return np + "/"
+}
+
+// toggleSlash 添加或者去掉路径末尾的 /
+func toggleSlash(p string) string {
+	if p == "/" {
+		return p
+	}
+	if p[len(p)-1] == '/' {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
 // ToPathStyle 将 URL 转换为指定风格的表示形式
 func ToPathStyle(path string, style PathStyleEnum) (string, string) {
 
@@ -137,48 +182,154 @@ func ToPathStyle(path string, style PathStyleEnum) (string, string) {
 		panic(errors.New("error path style"))
 	}
 
+	for _, seg := range parsePath(path) {
+		switch seg.kind {
+		case wildCardSegment:
+			p.addWildCard(seg.name)
+		case namedSegment:
+			p.addNamedPath(seg.name, seg.constraint)
+		default:
+			p.addKnownPath(seg.name)
+		}
+	}
+	return p.String(), p.wildCardName()
+}
+
+const (
+	knownSegment    = iota // 静态路径
+	namedSegment           // 命名参数
+	wildCardSegment        // 通配符
+)
+
+// pathSegment 路由地址中以 / 分隔的一段
+type pathSegment struct {
+	kind       int
+	name       string // 静态路径的内容，或者命名参数、通配符的名称
+	constraint string // 命名参数的约束条件
+}
+
+// parsePath 解析 echo、gin 和 {} 风格的路由地址
+func parsePath(path string) []pathSegment {
+
 	// 去掉开始的 / 字符，后面好计算
 	if path[0] == '/' {
 		path = path[1:]
 	}
 
+	var segments []pathSegment
 	for _, s := range strings.Split(path, "/") {
+		segments = append(segments, parseSegment(s))
+	}
+	return segments
+}
 
-		// 尾部的 '/' 特殊处理
-		if len(s) == 0 {
-			p.addKnownPath(s)
-			continue
-		}
+// parseSegment 解析路由地址中的一段
+func parseSegment(s string) pathSegment {
+
+	// 尾部的 '/' 特殊处理
+	if len(s) == 0 {
+		return pathSegment{kind: knownSegment}
+	}
 
-		switch s[0] {
-		case '{':
-			if s[len(s)-1] != '}' {
+	switch s[0] {
+	case '{':
+		if s[len(s)-1] != '}' {
+			panic(errors.New("error url path"))
+		}
+		if ss := strings.SplitN(s[1:len(s)-1], ":", 2); len(ss) > 1 {
+			if ss[0] == "*" {
+				return pathSegment{kind: wildCardSegment, name: ss[1]}
+			} else if ss[1] == "*" {
+				return pathSegment{kind: wildCardSegment, name: ss[0]}
+			} else if ss[0] == "" || ss[1] == "" {
 				panic(errors.New("error url path"))
-			}
-			if ss := strings.Split(s[1:len(s)-1], ":"); len(ss) > 1 {
-				if ss[0] == "*" {
-					p.addWildCard(ss[1])
-				} else if ss[1] == "*" {
-					p.addWildCard(ss[0])
-				} else {
-					panic(errors.New("error url path"))
-				}
-			} else if s[1] == '*' {
-				p.addWildCard(s[2 : len(s)-1])
 			} else {
-				p.addNamedPath(s[1 : len(s)-1])
+				getConstraint(ss[1]) // 尽早检查约束条件是否合法
+				return pathSegment{kind: namedSegment, name: ss[0], constraint: ss[1]}
 			}
-		case '*':
-			if s == "*" {
-				p.addWildCard("")
-			} else {
-				p.addWildCard(s[1:])
+		} else if s[1] == '*' {
+			return pathSegment{kind: wildCardSegment, name: s[2 : len(s)-1]}
+		} else {
+			return pathSegment{kind: namedSegment, name: s[1 : len(s)-1]}
+		}
+	case '*':
+		return pathSegment{kind: wildCardSegment, name: s[1:]}
+	case ':':
+		return pathSegment{kind: namedSegment, name: s[1:]}
+	default:
+		return pathSegment{kind: knownSegment, name: s}
+	}
+}
+
+// paramConstraint 命名参数的约束条件
+type paramConstraint struct {
+	expr    string
+	match   func(s string) bool
+	typ     string // swagger 参数类型
+	format  string // swagger 参数格式
+	pattern string // swagger 参数的正则表达式
+}
+
+var (
+	constraintsMutex sync.Mutex
+	constraints      = map[string]*paramConstraint{
+		"int":  {expr: "int", match: isInt, typ: "integer", format: "int64"},
+		"uuid": {expr: "uuid", match: isUUID, typ: "string", format: "uuid"},
+	}
+)
+
+// getConstraint 返回约束条件，除了内置的约束条件之外都当作正则表达式处理
+func getConstraint(expr string) *paramConstraint {
+	constraintsMutex.Lock()
+	defer constraintsMutex.Unlock()
+
+	if c, ok := constraints[expr]; ok {
+		return c
+	}
+
+	r, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Errorf("error path constraint %s: %v", expr, err))
+	}
+
+	c := &paramConstraint{expr: expr, match: r.MatchString, typ: "string", pattern: "^(?:" + expr + ")$"}
+	constraints[expr] = c
+	return c
+}
+
+// isInt 是否是十进制整数
+func isInt(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID 是否是 8-4-4-4-12 格式的 UUID
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
 			}
-		case ':':
-			p.addNamedPath(s[1:])
 		default:
-			p.addKnownPath(s)
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
 		}
 	}
-	return p.String(), p.wildCardName()
+	return true
 }
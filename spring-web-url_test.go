@@ -132,4 +132,41 @@ func TestToPathStyle(t *testing.T) {
 		assert.Equal(t, "/{a}/b/{c}/{*:e}", newPath)
 		assert.Equal(t, "e", wildCardName)
 	})
+	t.Run("/{a:int}/b/{c:[a-z-]+}", func(t *testing.T) {
+		newPath, wildCardName := SpringWeb.ToPathStyle("/{a:int}/b/{c:[a-z-]+}", SpringWeb.EchoPathStyle)
+		assert.Equal(t, "/:a/b/:c", newPath)
+		assert.Equal(t, "", wildCardName)
+		newPath, wildCardName = SpringWeb.ToPathStyle("/{a:int}/b/{c:[a-z-]+}", SpringWeb.GinPathStyle)
+		assert.Equal(t, "/:a/b/:c", newPath)
+		assert.Equal(t, "", wildCardName)
+		newPath, wildCardName = SpringWeb.ToPathStyle("/{a:int}/b/{c:[a-z-]+}", SpringWeb.JavaPathStyle)
+		assert.Equal(t, "/{a:int}/b/{c:[a-z-]+}", newPath)
+		assert.Equal(t, "", wildCardName)
+	})
+
+	t.Run("/{a:[}", func(t *testing.T) {
+		defer func() {
+			assert.Equal(t, recover() != nil, true)
+		}()
+		SpringWeb.ToPathStyle("/{a:[}", SpringWeb.EchoPathStyle)
+		t.Fatal("should panic")
+	})
+}
+
+func TestCleanPath(t *testing.T) {
+	for path, expect := range map[string]string{
+		"":              "/",
+		"/":             "/",
+		"a/b":           "/a/b",
+		"/a//b":         "/a/b",
+		"/a/./b/":       "/a/b/",
+		"/a/../b":       "/b",
+		"/a/b/..":       "/a",
+		"/../a":         "/a",
+		"//a//":         "/a/",
+		"/users/{id}/":  "/users/{id}/",
+		"/static/{*:f}": "/static/{*:f}",
+	} {
+		assert.Equal(t, SpringWeb.CleanPath(path), expect, path)
+	}
 }